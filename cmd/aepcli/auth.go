@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/auth"
+	"github.com/aep-dev/aepcli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func authCmd(configFile string, opts apiOptions) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "auth",
+		Short: "Log in to configured APIs",
+	}
+
+	// loginAPI returns the configured API, which must support logging in,
+	// and the directory its credentials are stored in.
+	loginAPI := func(name string) (config.API, string) {
+		cfg, err := config.ReadConfigFromFile(configFile)
+		if err != nil {
+			fmt.Printf("Error reading config file: %v\n", err)
+			os.Exit(1)
+		}
+		api, exists := cfg.APIs[name]
+		if !exists {
+			fmt.Printf("No API configuration found with name '%s'\n", name)
+			os.Exit(1)
+		}
+		if api.Auth == nil || api.Auth.AuthorizationURL == "" {
+			fmt.Printf("API '%s' has no auth.authorization_url configured, so there is nothing to log in to\n", name)
+			os.Exit(1)
+		}
+		dir, err := auth.CredentialsDir()
+		if err != nil {
+			fmt.Printf("Error getting credentials directory: %v\n", err)
+			os.Exit(1)
+		}
+		return api, dir
+	}
+
+	loginCmd := &cobra.Command{
+		Use:   "login [name]",
+		Short: "Log in to an API in the browser",
+		Long: "Log in with the OAuth 2.0 authorization code flow. The login page is opened in the browser,\n" +
+			"and the tokens are stored in the aepcli configuration directory. Expired access tokens are\n" +
+			"refreshed automatically.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			api, dir := loginAPI(args[0])
+			o, err := opts.withConfig(api)
+			if err != nil {
+				fmt.Printf("Error loading API configuration '%s': %v\n", args[0], err)
+				os.Exit(1)
+			}
+			// the token endpoint is called through the proxy, and with
+			// the certificates, of the API.
+			client, err := newHTTPClient(o)
+			if err != nil {
+				fmt.Printf("Error logging in to '%s': %v\n", args[0], err)
+				os.Exit(1)
+			}
+			if err := auth.Login(*api.Auth, dir, args[0], client, auth.OpenBrowser); err != nil {
+				fmt.Printf("Error logging in to '%s': %v\n", args[0], err)
+				os.Exit(1)
+			}
+			fmt.Printf("Logged in to '%s'\n", args[0])
+		},
+	}
+
+	logoutCmd := &cobra.Command{
+		Use:   "logout [name]",
+		Short: "Remove the stored credentials of an API",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, dir := loginAPI(args[0])
+			if err := auth.Logout(dir, args[0]); err != nil {
+				fmt.Printf("Error logging out of '%s': %v\n", args[0], err)
+				os.Exit(1)
+			}
+			fmt.Printf("Logged out of '%s'\n", args[0])
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status [name]",
+		Short: "Show whether you are logged in to an API",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, dir := loginAPI(args[0])
+			fmt.Println(loginStatus(auth.StoredToken(dir, args[0]), args[0], time.Now()))
+		},
+	}
+
+	c.AddCommand(loginCmd)
+	c.AddCommand(logoutCmd)
+	c.AddCommand(statusCmd)
+	return c
+}
+
+// loginStatus describes the stored token of the named API.
+func loginStatus(t *auth.Token, name string, now time.Time) string {
+	switch {
+	case t == nil:
+		return fmt.Sprintf("Not logged in to '%s'. Run: aepcli core auth login %s", name, name)
+	case t.Expiry.IsZero() || now.Before(t.Expiry):
+		return fmt.Sprintf("Logged in to '%s'. The access token expires at %s.", name, formatExpiry(t.Expiry))
+	case t.RefreshToken != "":
+		return fmt.Sprintf("Logged in to '%s'. The access token expired at %s, and will be refreshed on the next request.", name, formatExpiry(t.Expiry))
+	default:
+		return fmt.Sprintf("The session for '%s' expired at %s. Run: aepcli core auth login %s", name, formatExpiry(t.Expiry), name)
+	}
+}
+
+func formatExpiry(t time.Time) string {
+	if t.IsZero() {
+		return "an unknown time"
+	}
+	return t.Local().Format(time.RFC1123)
+}
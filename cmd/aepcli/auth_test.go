@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/auth"
+	"github.com/aep-dev/aepcli/internal/config"
+)
+
+func TestLoginStatus(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		token *auth.Token
+		want  string
+	}{
+		{
+			name: "logged out",
+			want: "Not logged in to 'bookstore'. Run: aepcli core auth login bookstore",
+		},
+		{
+			name:  "valid",
+			token: &auth.Token{AccessToken: "a", Expiry: now.Add(time.Hour)},
+			want:  "Logged in to 'bookstore'. The access token expires at",
+		},
+		{
+			name:  "refreshable",
+			token: &auth.Token{AccessToken: "a", RefreshToken: "r", Expiry: now.Add(-time.Hour)},
+			want:  "will be refreshed on the next request",
+		},
+		{
+			name:  "expired",
+			token: &auth.Token{AccessToken: "a", Expiry: now.Add(-time.Hour)},
+			want:  "Run: aepcli core auth login bookstore",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loginStatus(tt.token, "bookstore", now); !strings.Contains(got, tt.want) {
+				t.Errorf("loginStatus() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthDescription(t *testing.T) {
+	now := time.Now()
+	clientCredentials := &config.Auth{TokenURL: "https://auth.example.com/token", ClientID: "aepcli"}
+	authorizationCode := &config.Auth{AuthorizationURL: "https://auth.example.com/authorize", TokenURL: "https://auth.example.com/token", ClientID: "aepcli"}
+	tests := []struct {
+		name  string
+		api   config.API
+		token *auth.Token
+		want  []string
+	}{
+		{
+			name: "no auth",
+			api:  config.API{Name: "bookstore"},
+		},
+		{
+			name: "client credentials",
+			api:  config.API{Name: "bookstore", Auth: clientCredentials},
+			want: []string{"Auth: client credentials (token URL: https://auth.example.com/token, client ID: aepcli, scopes: [])"},
+		},
+		{
+			name: "authorization code, logged out",
+			api:  config.API{Name: "bookstore", Auth: authorizationCode},
+			want: []string{
+				"Auth: authorization code (authorization URL: https://auth.example.com/authorize, token URL: https://auth.example.com/token, client ID: aepcli, scopes: [])",
+				"Login: Not logged in to 'bookstore'. Run: aepcli core auth login bookstore",
+			},
+		},
+		{
+			name:  "authorization code, logged in",
+			api:   config.API{Name: "bookstore", Auth: authorizationCode},
+			token: &auth.Token{AccessToken: "a"},
+			want: []string{
+				"Auth: authorization code (authorization URL: https://auth.example.com/authorize, token URL: https://auth.example.com/token, client ID: aepcli, scopes: [])",
+				"Login: Logged in to 'bookstore'. The access token expires at an unknown time.",
+			},
+		},
+		{
+			name: "credential helper takes precedence",
+			api:  config.API{Name: "bookstore", Auth: authorizationCode, CredentialHelper: []string{"get-token", "bookstore"}},
+			want: []string{"Auth: credential helper (command: [get-token bookstore])"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authDescription(tt.api, tt.token, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("authDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
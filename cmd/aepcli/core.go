@@ -3,15 +3,22 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aep-dev/aep-lib-go/pkg/api"
 	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+	"github.com/aep-dev/aepcli/internal/auth"
 	"github.com/aep-dev/aepcli/internal/config"
+	"github.com/aep-dev/aepcli/internal/secret"
+	"github.com/aep-dev/aepcli/internal/service"
 	"github.com/spf13/cobra"
 )
 
-func handleCoreCommand(additionalArgs []string, configFile string) error {
+func handleCoreCommand(additionalArgs []string, configFile string, opts apiOptions) error {
 	coreCmd := &cobra.Command{
 		Use:   "core",
 		Args:  cobra.MinimumNArgs(1),
@@ -20,6 +27,8 @@ func handleCoreCommand(additionalArgs []string, configFile string) error {
 
 	coreCmd.AddCommand(openAPICommand())
 	coreCmd.AddCommand(configCmd(configFile))
+	coreCmd.AddCommand(copyCmd(configFile, opts))
+	coreCmd.AddCommand(authCmd(configFile, opts))
 
 	coreCmd.SetArgs(additionalArgs)
 	if err := coreCmd.Execute(); err != nil {
@@ -95,6 +104,14 @@ func configCmd(configFile string) *cobra.Command {
 	var headers []string
 	var pathPrefix string
 	var caCertPath string
+	var clientCert string
+	var clientKey string
+	var proxyURL string
+	var noProxy string
+	var unixSocket string
+	var protected bool
+	var readOnly bool
+	var allowedMethods []string
 
 	configCmd := &cobra.Command{
 		Use:   "config",
@@ -107,12 +124,20 @@ func configCmd(configFile string) *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			api = config.API{
-				Name:        args[0],
-				OpenAPIPath: openAPIPath,
-				ServerURL:   serverURL,
-				Headers:     headers,
-				PathPrefix:  pathPrefix,
-				CACertPath:  caCertPath,
+				Name:           args[0],
+				OpenAPIPath:    openAPIPath,
+				ServerURL:      serverURL,
+				Headers:        headers,
+				PathPrefix:     pathPrefix,
+				CACertPath:     caCertPath,
+				ClientCert:     clientCert,
+				ClientKey:      clientKey,
+				ProxyURL:       proxyURL,
+				NoProxy:        noProxy,
+				UnixSocket:     unixSocket,
+				Protected:      protected,
+				ReadOnly:       readOnly,
+				AllowedMethods: allowedMethods,
 			}
 			if err := config.WriteAPIWithName(configFile, api, overwrite); err != nil {
 				fmt.Printf("Error writing API config: %v\n", err)
@@ -127,6 +152,14 @@ func configCmd(configFile string) *cobra.Command {
 	addCmd.Flags().StringVar(&serverURL, "server-url", "", "Server URL")
 	addCmd.Flags().StringVar(&pathPrefix, "path-prefix", "", "Path prefix")
 	addCmd.Flags().StringVar(&caCertPath, "ca-cert", "", "Path to custom CA certificate file (PEM format)")
+	addCmd.Flags().StringVar(&clientCert, "client-cert", "", "Path to a client certificate (PEM, or a PKCS#12 .p12/.pfx bundle) for mutual TLS")
+	addCmd.Flags().StringVar(&clientKey, "client-key", "", "Path to the PEM private key of the client certificate")
+	addCmd.Flags().StringVar(&proxyURL, "proxy-url", "", "URL of an http, https or socks5 proxy to connect through")
+	addCmd.Flags().StringVar(&noProxy, "no-proxy", "", "Comma-separated hosts to connect to without the proxy, like NO_PROXY")
+	addCmd.Flags().StringVar(&unixSocket, "unix-socket", "", "Path of a Unix domain socket the server listens on")
+	addCmd.Flags().BoolVar(&protected, "protected", false, "Require every change to be confirmed by typing the path of the resource, or the name of the API for changes to many resources")
+	addCmd.Flags().BoolVar(&readOnly, "read-only", false, "Block every command that changes resources")
+	addCmd.Flags().StringArrayVar(&allowedMethods, "allowed-method", []string{}, "Only allow the given command, e.g. get or list. Can be repeated.")
 	addCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing configuration")
 
 	readCmd := &cobra.Command{
@@ -149,9 +182,15 @@ func configCmd(configFile string) *cobra.Command {
 			fmt.Printf("Name: %s\n", api.Name)
 			fmt.Printf("OpenAPI Path: %s\n", api.OpenAPIPath)
 			fmt.Printf("Server URL: %s\n", api.ServerURL)
-			fmt.Printf("Headers: %v\n", api.Headers)
+			fmt.Printf("Headers: %v\n", maskHeaders(api.Headers))
 			fmt.Printf("Path Prefix: %s\n", api.PathPrefix)
 			fmt.Printf("CA Certificate Path: %s\n", api.CACertPath)
+			printClientCert(api)
+			printNetwork(api)
+			fmt.Printf("Protected: %v\n", api.Protected)
+			fmt.Printf("Read Only: %v\n", api.ReadOnly)
+			fmt.Printf("Allowed Methods: %v\n", api.AllowedMethods)
+			printAuth(api)
 		},
 	}
 
@@ -175,9 +214,15 @@ func configCmd(configFile string) *cobra.Command {
 				fmt.Printf("Name: %s\n", api.Name)
 				fmt.Printf("OpenAPI Path: %s\n", api.OpenAPIPath)
 				fmt.Printf("Server URL: %s\n", api.ServerURL)
-				fmt.Printf("Headers: %v\n", api.Headers)
+				fmt.Printf("Headers: %v\n", maskHeaders(api.Headers))
 				fmt.Printf("Path Prefix: %s\n", api.PathPrefix)
 				fmt.Printf("CA Certificate Path: %s\n", api.CACertPath)
+				printClientCert(api)
+				printNetwork(api)
+				fmt.Printf("Protected: %v\n", api.Protected)
+				fmt.Printf("Read Only: %v\n", api.ReadOnly)
+				fmt.Printf("Allowed Methods: %v\n", api.AllowedMethods)
+				printAuth(api)
 				fmt.Println()
 			}
 		},
@@ -189,3 +234,142 @@ func configCmd(configFile string) *cobra.Command {
 
 	return configCmd
 }
+
+// maskHeaders hides the values of headers that do not refer to secrets.
+func maskHeaders(headers []string) []string {
+	masked := make([]string, 0, len(headers))
+	for _, h := range headers {
+		k, v, _ := strings.Cut(h, "=")
+		masked = append(masked, k+"="+secret.Mask(v))
+	}
+	return masked
+}
+
+// printClientCert prints the client certificate of an API, if it has one.
+func printClientCert(api config.API) {
+	if api.ClientCert == "" {
+		return
+	}
+	fmt.Printf("Client Certificate: %s\n", api.ClientCert)
+	if api.ClientKey != "" {
+		fmt.Printf("Client Key: %s\n", api.ClientKey)
+	}
+}
+
+// printNetwork prints how aepcli connects to an API, without the password of
+// the proxy.
+func printNetwork(api config.API) {
+	if api.ProxyURL != "" {
+		proxy := api.ProxyURL
+		if u, err := url.Parse(proxy); err == nil {
+			proxy = u.Redacted()
+		}
+		fmt.Printf("Proxy URL: %s\n", proxy)
+	}
+	if api.NoProxy != "" {
+		fmt.Printf("No Proxy: %s\n", api.NoProxy)
+	}
+	if api.UnixSocket != "" {
+		fmt.Printf("Unix Socket: %s\n", api.UnixSocket)
+	}
+}
+
+// printAuth prints the authentication configuration of an API, without its
+// secrets, and whether the user is logged in to it.
+func printAuth(api config.API) {
+	var token *auth.Token
+	if api.Auth != nil && api.Auth.AuthorizationURL != "" && len(api.CredentialHelper) == 0 {
+		if dir, err := auth.CredentialsDir(); err == nil {
+			token = auth.StoredToken(dir, api.Name)
+		}
+	}
+	for _, line := range authDescription(api, token, time.Now()) {
+		fmt.Println(line)
+	}
+	if len(api.Credentials) > 0 {
+		schemes := make([]string, 0, len(api.Credentials))
+		for name := range api.Credentials {
+			schemes = append(schemes, name)
+		}
+		sort.Strings(schemes)
+		fmt.Printf("Credentials: %v\n", schemes)
+	}
+}
+
+// authDescription describes the flow requests to the API are authenticated
+// with, in the order newAuthenticator picks it, and the stored token of users
+// who log in.
+func authDescription(api config.API, token *auth.Token, now time.Time) []string {
+	if len(api.CredentialHelper) > 0 {
+		return []string{fmt.Sprintf("Auth: credential helper (command: %v)", api.CredentialHelper)}
+	}
+	a := api.Auth
+	if a == nil {
+		return nil
+	}
+	if a.AuthorizationURL != "" {
+		return []string{
+			fmt.Sprintf("Auth: authorization code (authorization URL: %s, token URL: %s, client ID: %s, scopes: %v)", a.AuthorizationURL, a.TokenURL, a.ClientID, a.Scopes),
+			fmt.Sprintf("Login: %s", loginStatus(token, api.Name, now)),
+		}
+	}
+	return []string{fmt.Sprintf("Auth: client credentials (token URL: %s, client ID: %s, scopes: %v)", a.TokenURL, a.ClientID, a.Scopes)}
+}
+
+func copyCmd(configFile string, opts apiOptions) *cobra.Command {
+	var copyOpts service.CopyOptions
+
+	c := &cobra.Command{
+		Use:   "copy [src-alias] [dst-alias] [resource-path]",
+		Short: "Copy a resource between two configured APIs",
+		Long: "Read a resource from one configured API, and create or update it in another.\n" +
+			"Fields managed by the server are not copied.",
+		Args: cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.ReadConfigFromFile(configFile)
+			if err != nil {
+				fmt.Printf("Error reading config file: %v\n", err)
+				os.Exit(1)
+			}
+			var services []*service.ServiceCommand
+			for _, alias := range args[:2] {
+				api, exists := cfg.APIs[alias]
+				if !exists {
+					fmt.Printf("No API configuration found with name '%s'\n", alias)
+					os.Exit(1)
+				}
+				// the server URL and path prefix flags can not apply
+				// to two different APIs, so the configured ones are used.
+				o := opts
+				o.serverURL = ""
+				o.pathPrefix = ""
+				o, err := o.withConfig(api)
+				if err != nil {
+					fmt.Printf("Error loading API configuration '%s': %v\n", alias, err)
+					os.Exit(1)
+				}
+				s, err := newServiceCommand(o)
+				if err != nil {
+					fmt.Printf("Error loading API '%s': %v\n", alias, err)
+					os.Exit(1)
+				}
+				services = append(services, s)
+			}
+
+			result, err := service.Copy(services[0], services[1], args[2], copyOpts)
+			if result != nil {
+				fmt.Println(result.Output)
+			}
+			if err != nil {
+				fmt.Printf("Error copying %s: %v\n", args[2], err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	c.Flags().BoolVar(&copyOpts.Recursive, "recursive", false, "Copy the descendants of the resource as well")
+	c.Flags().StringVar(&copyOpts.Parent, "parent", "", "Path of the parent to copy the resource into, if different from the source")
+	c.Flags().BoolVar(&copyOpts.Plan, "plan", false, "Show the changes that would be made, without making them")
+	c.Flags().BoolVar(&copyOpts.Yes, "yes", false, "Do not ask for confirmation")
+	return c
+}
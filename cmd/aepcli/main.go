@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/aep-dev/aep-lib-go/pkg/api"
 	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+	"github.com/aep-dev/aepcli/internal/auth"
+	"github.com/aep-dev/aepcli/internal/cassette"
 	"github.com/aep-dev/aepcli/internal/config"
+	"github.com/aep-dev/aepcli/internal/har"
+	"github.com/aep-dev/aepcli/internal/redact"
+	"github.com/aep-dev/aepcli/internal/secret"
 	"github.com/aep-dev/aepcli/internal/service"
+	"github.com/aep-dev/aepcli/internal/timing"
 
 	"github.com/spf13/cobra"
 )
@@ -19,34 +33,361 @@ const (
 	CODE_OK                  = 0
 	CODE_ERR                 = 1
 	CODE_HTTP_ERROR_RESPONSE = 2
+	// CODE_DIFF is returned when a compared resource differs,
+	// similar to diff(1).
+	CODE_DIFF = 1
+	// CODE_INTERRUPTED is returned when the command is cancelled with
+	// Ctrl-C, like shells report processes killed by SIGINT.
+	CODE_INTERRUPTED = 130
 )
 
 func main() {
 	code, err := aepcli(os.Args[1:])
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		if code == CODE_OK {
+			code = CODE_ERR
+		}
 	}
 	os.Exit(code)
 }
 
+// apiOptions configure how an API is loaded and called. They are populated
+// from flags, and unset values are taken from the API configuration.
+type apiOptions struct {
+	// name is the name of the configured API, if any.
+	name        string
+	openAPIPath string
+	serverURL   string
+	pathPrefix  string
+	caCertPath  string
+	clientCert  service.ClientCertificate
+	network     service.Network
+	headers     []string
+	dryRun      bool
+	logHTTP     bool
+	insecure    bool
+	protected   bool
+	policy      service.Policy
+	auth        *config.Auth
+	credentials map[string]string
+	// credentialHelper is the command printing the credentials of the API.
+	credentialHelper []string
+	retry            service.RetryPolicy
+	// retriesSet and retryMaxWaitSet are true if the retry flags were set,
+	// and take precedence over the configuration.
+	retriesSet      bool
+	retryMaxWaitSet bool
+	// timeout is how long a request may take. It is 0 for no timeout.
+	timeout    time.Duration
+	timeoutSet bool
+	// record and replay are the cassette files interactions are recorded
+	// to, or replayed from, and har the HAR file they are written to.
+	record string
+	replay string
+	har    string
+	// redactHeaders are patterns of headers and query parameters, and
+	// redactFields names of body fields, that are redacted in addition to
+	// the defaults.
+	redactHeaders []string
+	redactFields  []string
+	// printAs is the format requests are printed in, and printMasked masks
+	// their credentials.
+	printAs     string
+	printCurl   bool
+	printMasked bool
+	// timing is the format the timings of requests are reported in, if
+	// they are.
+	timing string
+}
+
+// withConfig returns a copy of the options, with unset values taken from the
+// configured API.
+func (o apiOptions) withConfig(api config.API) (apiOptions, error) {
+	cd, err := config.ConfigDir()
+	if err != nil {
+		return o, fmt.Errorf("unable to get config directory: %w", err)
+	}
+	if filepath.IsAbs(api.OpenAPIPath) || strings.HasPrefix(api.OpenAPIPath, "http") {
+		o.openAPIPath = api.OpenAPIPath
+	} else {
+		o.openAPIPath = filepath.Join(cd, api.OpenAPIPath)
+	}
+	if o.pathPrefix == "" {
+		o.pathPrefix = api.PathPrefix
+	}
+	if o.caCertPath == "" {
+		o.caCertPath = api.CACertPath
+	}
+	if o.serverURL == "" {
+		o.serverURL = api.ServerURL
+	}
+	if o.clientCert.CertPath == "" && o.clientCert.KeyPath == "" {
+		o.clientCert = service.ClientCertificate{CertPath: api.ClientCert, KeyPath: api.ClientKey, Password: api.ClientCertPassword}
+	}
+	if o.network.ProxyURL == "" {
+		o.network.ProxyURL = api.ProxyURL
+	}
+	if o.network.NoProxy == "" {
+		o.network.NoProxy = api.NoProxy
+	}
+	if o.network.UnixSocket == "" {
+		o.network.UnixSocket = api.UnixSocket
+	}
+	o.headers = append(append([]string{}, o.headers...), api.Headers...)
+	if len(api.RedactHeaders) > 0 {
+		o.redactHeaders = append(append([]string{}, o.redactHeaders...), api.RedactHeaders...)
+	}
+	if len(api.RedactFields) > 0 {
+		o.redactFields = append(append([]string{}, o.redactFields...), api.RedactFields...)
+	}
+	o.protected = api.Protected
+	o.policy = service.Policy{ReadOnly: api.ReadOnly, AllowedMethods: api.AllowedMethods}
+	o.auth = api.Auth
+	o.credentials = api.Credentials
+	o.credentialHelper = api.CredentialHelper
+	if api.Retries != nil && !o.retriesSet {
+		o.retry.Retries = *api.Retries
+	}
+	if api.RetryMaxWait != "" && !o.retryMaxWaitSet {
+		d, err := time.ParseDuration(api.RetryMaxWait)
+		if err != nil {
+			return o, fmt.Errorf("invalid retry_max_wait %q: %w", api.RetryMaxWait, err)
+		}
+		o.retry.MaxWait = d
+	}
+	if api.Timeout != "" && !o.timeoutSet {
+		d, err := time.ParseDuration(api.Timeout)
+		if err != nil {
+			return o, fmt.Errorf("invalid timeout %q: %w", api.Timeout, err)
+		}
+		o.timeout = d
+	}
+	o.name = api.Name
+	return o, nil
+}
+
+// newServiceCommand loads the OpenAPI definition and creates a service
+// command for it.
+func newServiceCommand(o apiOptions) (*service.ServiceCommand, error) {
+	if o.replay != "" && isURL(o.openAPIPath) {
+		// the definition is not part of the cassette, so fetching it would
+		// need the network that replaying does without.
+		return nil, fmt.Errorf("--replay needs a local OpenAPI definition, but %s is a URL\n\nTo fix this issue:\n  1. Download the definition, and pass its path instead of the URL, or set openapi_path to it", o.openAPIPath)
+	}
+	// the definition is fetched with the proxy, certificates and timeout of
+	// the API.
+	client, err := newHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := readFileOrURL(o.openAPIPath, client)
+	if err != nil {
+		return nil, err
+	}
+	var oas *openapi.OpenAPI
+	if err := json.Unmarshal(raw, &oas); err != nil {
+		return nil, fmt.Errorf("unable to fetch openapi: %w", err)
+	}
+	security, err := auth.ParseSecurity(raw)
+	if err != nil {
+		return nil, err
+	}
+	api, err := api.GetAPI(oas, o.serverURL, o.pathPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get api: %w", err)
+	}
+	headersMap, err := parseHeaders(o.headers)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse headers: %w", err)
+	}
+	s, err := service.NewServiceCommand(api, headersMap, o.dryRun, o.logHTTP, o.insecure, o.caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create service command: %w", err)
+	}
+	// the transport of the service is wrapped below to time, record and
+	// replay requests; the client itself is used to fetch tokens.
+	serviceClient := *client
+	s.Client = &serviceClient
+	s.OpenAPI = oas
+	s.Name = o.name
+	s.Protected = o.protected
+	s.Policy = o.policy
+	s.Retry = o.retry
+	if o.printCurl && o.printAs == "" {
+		o.printAs = "curl"
+	}
+	if o.printAs != "" {
+		if err := service.ValidatePrintFormat(o.printAs); err != nil {
+			return nil, err
+		}
+		s.PrintAs = o.printAs
+		s.PrintMasked = o.printMasked
+	}
+	if o.record != "" && o.replay != "" {
+		return nil, fmt.Errorf("--record and --replay can not be used together")
+	}
+	redactor, err := newRedactor(o, raw, security)
+	if err != nil {
+		return nil, err
+	}
+	s.Redact = redactor
+	// the replayer takes the place of the network, so replayed requests are
+	// timed and written to HAR files too.
+	if o.replay != "" {
+		if s.Client.Transport, err = cassette.NewReplayer(o.replay, redactor); err != nil {
+			return nil, err
+		}
+	}
+	if o.timing != "" {
+		// timings are reported to stderr, so the output can still be parsed.
+		if s.Timing, err = timing.NewTransport(s.Client.Transport, os.Stderr, o.timing, redactor); err != nil {
+			return nil, err
+		}
+		s.Client.Transport = s.Timing
+	}
+	if o.record != "" {
+		if s.Client.Transport, err = cassette.NewRecorder(o.record, s.Client.Transport, redactor); err != nil {
+			return nil, err
+		}
+	}
+	if o.har != "" {
+		s.Client.Transport = har.NewRecorder(o.har, s.Client.Transport, redactor, buildVersion())
+	}
+	if o.replay != "" {
+		// replayed requests are not sent, so they do not need credentials.
+		return s, nil
+	}
+	if o.auth != nil || len(o.credentialHelper) > 0 {
+		s.Auth, err = newAuthenticator(o, client)
+		if err != nil {
+			return nil, err
+		}
+	} else if security != nil {
+		s.Auth = auth.NewSchemes(security, o.name, o.credentials)
+	}
+	return s, nil
+}
+
+// newHTTPClient returns the client the requests of the API are sent with,
+// before they are timed, recorded or replayed.
+func newHTTPClient(o apiOptions) (*http.Client, error) {
+	clientCert := o.clientCert
+	var err error
+	if clientCert.Password, err = secret.Resolve(clientCert.Password); err != nil {
+		return nil, fmt.Errorf("unable to resolve client certificate password: %w", err)
+	}
+	client, err := service.NewHTTPClient(service.ClientOptions{
+		Insecure:   o.insecure,
+		CACertPath: o.caCertPath,
+		ClientCert: clientCert,
+		Network:    o.network,
+		Timeout:    o.timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create HTTP client: %w", err)
+	}
+	return client, nil
+}
+
+// newRedactor returns the redactor of the requests that are logged, printed
+// and recorded: the configured headers, the headers and parameters of the
+// API keys of the OpenAPI definition, and the writeOnly and password fields
+// of its schemas are redacted.
+func newRedactor(o apiOptions, raw []byte, security *auth.Security) (*redact.Redactor, error) {
+	patterns := append([]string{}, o.redactHeaders...)
+	if security != nil {
+		for _, scheme := range security.Schemes {
+			if scheme.Type == "apiKey" && scheme.ParamName != "" {
+				patterns = append(patterns, scheme.ParamName)
+			}
+		}
+	}
+	fields, err := redact.SensitiveFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read sensitive fields: %w", err)
+	}
+	return redact.New(patterns, append(fields, o.redactFields...)), nil
+}
+
+// isURL returns whether the OpenAPI definition is read from an http(s) URL.
+func isURL(pathOrURL string) bool {
+	u, err := url.Parse(pathOrURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// readFileOrURL reads the OpenAPI definition from a file or an http(s) URL,
+// which is fetched with client.
+func readFileOrURL(pathOrURL string, client *http.Client) ([]byte, error) {
+	if !isURL(pathOrURL) {
+		raw, err := os.ReadFile(pathOrURL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch openapi: unable to read file or URL: %w", err)
+		}
+		return raw, nil
+	}
+	resp, err := client.Get(pathOrURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch openapi: unable to read file or URL: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch openapi: status %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch openapi: unable to read file or URL: %w", err)
+	}
+	return raw, nil
+}
+
+// newAuthenticator returns the authenticator for the auth configuration of
+// the API: a credential helper if there is one, users log in if there is an
+// authorization URL, otherwise the client credentials are used. Tokens are
+// requested with client.
+func newAuthenticator(o apiOptions, client *http.Client) (service.Authenticator, error) {
+	if len(o.credentialHelper) > 0 {
+		cacheDir, err := auth.DefaultCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get token cache directory: %w", err)
+		}
+		return auth.NewCredentialHelper(o.credentialHelper, cacheDir)
+	}
+	if o.auth.AuthorizationURL != "" {
+		dir, err := auth.CredentialsDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get credentials directory: %w", err)
+		}
+		a, err := auth.NewAuthorizationCode(*o.auth, dir, o.name)
+		if err != nil {
+			return nil, err
+		}
+		a.Client = client
+		return a, nil
+	}
+	cacheDir, err := auth.DefaultCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get token cache directory: %w", err)
+	}
+	c, err := auth.NewClientCredentials(*o.auth, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	c.Client = client
+	return c, nil
+}
+
 func aepcli(args []string) (int, error) {
-	var dryRun bool
-	var logHTTP bool
-	var insecure bool
-	var caCertPath string
+	var opts apiOptions
 	var logLevel string
 	var fileAliasOrCore string
 	var additionalArgs []string
-	var headers []string
-	var pathPrefix string
-	var serverURL string
 	var configFileVar string
-	var s *service.ServiceCommand
 
 	rootCmd := &cobra.Command{
-		Use:  "aepcli [host or api alias] [resource or --help]",
-		Args: cobra.MinimumNArgs(1),
+		Use:     "aepcli [host or api alias] [resource or --help]",
+		Version: buildVersion(),
+		Args:    cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			fileAliasOrCore = args[0]
 			if len(args) > 1 {
@@ -61,20 +402,45 @@ func aepcli(args []string) (int, error) {
 	}
 
 	rootCmd.Flags().SetInterspersed(false) // allow sub parsers to parse subsequent flags after the resource
-	rootCmd.PersistentFlags().StringArrayVar(&headers, "header", []string{}, "Specify headers in the format key=value")
+	rootCmd.PersistentFlags().StringArrayVar(&opts.headers, "header", []string{}, "Specify headers in the format key=value")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Set the logging level (debug, info, warn, error)")
-	rootCmd.PersistentFlags().BoolVar(&logHTTP, "log-http", false, "Set to true to log HTTP requests. This can be helpful when attempting to write your own code or debug.")
-	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Set to true to not make any changes. This can be helpful when paired with log-http to just view http requests instead of perform them.")
-	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false, "Set to true to skip TLS certificate verification. Use with caution.")
-	rootCmd.PersistentFlags().StringVar(&caCertPath, "ca-cert", "", "Path to custom CA certificate file (PEM format) to add to the trusted certificate pool")
-	rootCmd.PersistentFlags().StringVar(&pathPrefix, "path-prefix", "", "Specify a path prefix that is prepended to all paths in the openapi schema. This will strip them when evaluating the resource hierarchy paths.")
-	rootCmd.PersistentFlags().StringVar(&serverURL, "server-url", "", "Specify a URL to use for the server. If not specified, the first server URL in the OpenAPI definition will be used.")
+	rootCmd.PersistentFlags().BoolVar(&opts.logHTTP, "log-http", false, "Set to true to log HTTP requests. This can be helpful when attempting to write your own code or debug.")
+	rootCmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "Set to true to not make any changes. This can be helpful when paired with log-http to just view http requests instead of perform them.")
+	rootCmd.PersistentFlags().BoolVar(&opts.insecure, "insecure", false, "Set to true to skip TLS certificate verification. Use with caution.")
+	rootCmd.PersistentFlags().StringVar(&opts.caCertPath, "ca-cert", "", "Path to custom CA certificate file (PEM format) to add to the trusted certificate pool")
+	rootCmd.PersistentFlags().StringVar(&opts.clientCert.CertPath, "client-cert", "", "Path to a client certificate (PEM, or a PKCS#12 .p12/.pfx bundle) for servers that require mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&opts.clientCert.KeyPath, "client-key", "", "Path to the PEM private key of the client certificate, if it is not in the certificate file")
+	rootCmd.PersistentFlags().StringVar(&opts.clientCert.Password, "client-cert-password", "", "Password of the PKCS#12 bundle, e.g. ${env:P12_PASSWORD}")
+	rootCmd.PersistentFlags().StringVar(&opts.network.ProxyURL, "proxy-url", "", "URL of an http, https or socks5 proxy to connect through, e.g. socks5://localhost:1080. Defaults to the HTTP_PROXY and HTTPS_PROXY environment variables.")
+	rootCmd.PersistentFlags().StringVar(&opts.network.NoProxy, "no-proxy", "", "Comma-separated hosts, domains and CIDR ranges to connect to without the proxy, like NO_PROXY")
+	rootCmd.PersistentFlags().StringVar(&opts.network.UnixSocket, "unix-socket", "", "Path of a Unix domain socket to send requests to, e.g. for local development servers")
+	rootCmd.PersistentFlags().StringVar(&opts.pathPrefix, "path-prefix", "", "Specify a path prefix that is prepended to all paths in the openapi schema. This will strip them when evaluating the resource hierarchy paths.")
+	rootCmd.PersistentFlags().StringVar(&opts.serverURL, "server-url", "", "Specify a URL to use for the server. If not specified, the first server URL in the OpenAPI definition will be used.")
+	rootCmd.PersistentFlags().IntVar(&opts.retry.Retries, "retries", 0, "Number of times to retry requests that failed with a transient error, such as 429 or 503. Changes are only retried if they have a request_id.")
+	rootCmd.PersistentFlags().DurationVar(&opts.retry.MaxWait, "retry-max-wait", 30*time.Second, "Longest time to wait before a retry")
+	rootCmd.PersistentFlags().DurationVar(&opts.timeout, "timeout", 0, "Longest time a request may take, e.g. 30s or 5m. 0, the default, disables the timeout.")
+	rootCmd.PersistentFlags().StringVar(&opts.record, "record", "", "Record every request and response to a cassette file, e.g. cassette.yaml. Interactions are appended if the file exists.")
+	rootCmd.PersistentFlags().StringVar(&opts.replay, "replay", "", "Serve responses from a cassette file recorded with --record, without a network")
+	rootCmd.PersistentFlags().StringVar(&opts.har, "har", "", "Write every request and response, with headers, bodies and timings, to an HTTP Archive (HAR) file, e.g. out.har")
+	rootCmd.PersistentFlags().StringArrayVar(&opts.redactHeaders, "redact-header", []string{}, "Pattern of headers and query parameters, e.g. 'X-*-Signature', redacted in logs, printed requests, cassettes and HAR files, in addition to Authorization, Cookie, *-Key and other credentials. Can be repeated.")
+	rootCmd.PersistentFlags().StringArrayVar(&opts.redactFields, "redact-field", []string{}, "Name of a body field redacted in logs, printed requests and HAR files, in addition to writeOnly and password fields. Can be repeated.")
+	rootCmd.PersistentFlags().BoolVar(&opts.printCurl, "print-curl", false, "Print every request as a curl command. Same as --print-as=curl.")
+	rootCmd.PersistentFlags().StringVar(&opts.printAs, "print-as", "", fmt.Sprintf("Print every request as a command or code snippet: %s. Paired with --dry-run, requests are printed but not sent.", strings.Join(service.PrintFormats(), ", ")))
+	rootCmd.PersistentFlags().BoolVar(&opts.printMasked, "print-masked", false, "Mask credentials, such as the Authorization header, in requests printed with --print-as")
+	rootCmd.PersistentFlags().StringVar(&opts.timing, "timing", "", "Report the DNS, connect, TLS, server and transfer time of every request, and their totals, to stderr: human (the default) or json. The format must be given with =, e.g. --timing=json.")
+	rootCmd.PersistentFlags().Lookup("timing").NoOptDefVal = "human"
 	rootCmd.PersistentFlags().StringVar(&configFileVar, "config", "", "Path to config file")
 	rootCmd.SetArgs(args)
 
 	if err := rootCmd.Execute(); err != nil {
 		return CODE_OK, err
 	}
+	if printed, _ := rootCmd.Flags().GetBool("version"); printed {
+		return CODE_OK, nil
+	}
+	opts.retriesSet = rootCmd.PersistentFlags().Changed("retries")
+	opts.retryMaxWaitSet = rootCmd.PersistentFlags().Changed("retry-max-wait")
+	opts.timeoutSet = rootCmd.PersistentFlags().Changed("timeout")
 
 	if configFileVar != "" {
 		configFile = configFileVar
@@ -89,55 +455,52 @@ func aepcli(args []string) (int, error) {
 		return CODE_ERR, fmt.Errorf("unable to read config: %v", err)
 	}
 
+	if _, ok := c.APIs[fileAliasOrCore]; !ok && opts.timing != "" && slices.Contains(timing.Formats, fileAliasOrCore) {
+		// the format of --timing is optional, so it is only read with =.
+		return CODE_ERR, fmt.Errorf("%s was read as the API, not the format of --timing\n\nTo fix this issue:\n  1. Pass the format with =, e.g. --timing=%s", fileAliasOrCore, fileAliasOrCore)
+	}
+
 	if fileAliasOrCore == "core" {
-		return CODE_OK, handleCoreCommand(additionalArgs, configFile)
+		return CODE_OK, handleCoreCommand(additionalArgs, configFile, opts)
 	}
 
+	opts.openAPIPath = fileAliasOrCore
 	if api, ok := c.APIs[fileAliasOrCore]; ok {
-		cd, err := config.ConfigDir()
+		opts, err = opts.withConfig(api)
 		if err != nil {
-			return CODE_ERR, fmt.Errorf("unable to get config directory: %w", err)
-		}
-		if filepath.IsAbs(api.OpenAPIPath) || strings.HasPrefix(api.OpenAPIPath, "http") {
-			fileAliasOrCore = api.OpenAPIPath
-		} else {
-			fileAliasOrCore = filepath.Join(cd, api.OpenAPIPath)
-		}
-		if pathPrefix == "" {
-			pathPrefix = api.PathPrefix
-		}
-		if caCertPath == "" {
-			caCertPath = api.CACertPath
+			return CODE_ERR, err
 		}
-		headers = append(headers, api.Headers...)
-		serverURL = api.ServerURL
 	}
 
-	oas, err := openapi.FetchOpenAPI(fileAliasOrCore)
+	s, err := newServiceCommand(opts)
 	if err != nil {
-		return CODE_ERR, fmt.Errorf("unable to fetch openapi: %w", err)
-	}
-	api, err := api.GetAPI(oas, serverURL, pathPrefix)
-	if err != nil {
-		return CODE_ERR, fmt.Errorf("unable to get api: %w", err)
-	}
-	headersMap, err := parseHeaders(headers)
-	if err != nil {
-		return CODE_ERR, fmt.Errorf("unable to parse headers: %w", err)
+		return CODE_ERR, err
 	}
 
-	s, err = service.NewServiceCommand(api, headersMap, dryRun, logHTTP, insecure, caCertPath)
-	if err != nil {
-		return CODE_ERR, fmt.Errorf("unable to create service command: %w", err)
-	}
+	// Ctrl-C cancels the requests in flight. A second Ctrl-C exits
+	// immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	result, err := s.Execute(additionalArgs)
+	result, err := s.ExecuteContext(ctx, additionalArgs)
+	if ctx.Err() != nil {
+		if result != nil && result.Output != "" {
+			fmt.Println(result.Output)
+		}
+		return CODE_INTERRUPTED, fmt.Errorf("interrupted")
+	}
 	returnCode := CODE_OK
 	output := ""
 	if result != nil {
 		output = result.Output
 		if result.StatusCode != 0 && result.StatusCode/100 != 2 {
 			returnCode = CODE_HTTP_ERROR_RESPONSE
+		} else if result.HasDiff {
+			returnCode = CODE_DIFF
 		}
 	}
 	fmt.Println(output)
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/cassette"
+	"github.com/aep-dev/aepcli/internal/config"
+	"github.com/aep-dev/aepcli/internal/service"
 )
 
 func TestAepcli(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	tests := []struct {
 		name    string
 		args    []string
@@ -18,6 +30,16 @@ func TestAepcli(t *testing.T) {
 			wantErr: true,
 			errMsg:  "requires at least 1 arg(s), only received 0",
 		},
+		{
+			name:    "timing format without =",
+			args:    []string{"--timing", "json", "bookstore"},
+			wantErr: true,
+			errMsg:  "json was read as the API, not the format of --timing",
+		},
+		{
+			name: "version",
+			args: []string{"--version"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -45,3 +67,206 @@ func TestAepcli(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIOptionsWithConfig(t *testing.T) {
+	api := config.API{
+		Name:        "bookstore",
+		OpenAPIPath: "https://bookstore.example.com/openapi.json",
+		ServerURL:   "https://bookstore.example.com",
+		Headers:     []string{"X-API-CLIENT=aepcli"},
+		PathPrefix:  "/bookstore",
+		CACertPath:  "/path/to/ca.pem",
+		Protected:   true,
+		ReadOnly:    true,
+	}
+
+	o, err := apiOptions{headers: []string{"X-FLAG=1"}}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	want := apiOptions{
+		name:        "bookstore",
+		openAPIPath: "https://bookstore.example.com/openapi.json",
+		serverURL:   "https://bookstore.example.com",
+		pathPrefix:  "/bookstore",
+		caCertPath:  "/path/to/ca.pem",
+		headers:     []string{"X-FLAG=1", "X-API-CLIENT=aepcli"},
+		protected:   true,
+		policy:      service.Policy{ReadOnly: true},
+	}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("withConfig() = %+v, want %+v", o, want)
+	}
+}
+
+func TestAPIOptionsWithConfig_ServerURL(t *testing.T) {
+	api := config.API{OpenAPIPath: "/openapi.json", ServerURL: "https://bookstore.example.com", PathPrefix: "/bookstore"}
+
+	// --server-url and --path-prefix take precedence over the configuration,
+	// e.g. to call a local server with the configuration of a remote API.
+	o, err := apiOptions{serverURL: "http://localhost:8081", pathPrefix: "/v2"}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	if o.serverURL != "http://localhost:8081" || o.pathPrefix != "/v2" {
+		t.Errorf("withConfig() = %q %q, want the flag values", o.serverURL, o.pathPrefix)
+	}
+
+	o, err = apiOptions{}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	if o.serverURL != "https://bookstore.example.com" || o.pathPrefix != "/bookstore" {
+		t.Errorf("withConfig() = %q %q, want the configured values", o.serverURL, o.pathPrefix)
+	}
+}
+
+func TestAPIOptionsWithConfig_Retry(t *testing.T) {
+	retries := 5
+	api := config.API{OpenAPIPath: "/openapi.json", Retries: &retries, RetryMaxWait: "1m"}
+	defaults := service.RetryPolicy{Retries: 3, MaxWait: 30 * time.Second}
+
+	o, err := apiOptions{retry: defaults}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	if want := (service.RetryPolicy{Retries: 5, MaxWait: time.Minute}); o.retry != want {
+		t.Errorf("retry = %+v, want %+v", o.retry, want)
+	}
+
+	// flags take precedence over the configuration.
+	o, err = apiOptions{retry: service.RetryPolicy{Retries: 0, MaxWait: time.Second}, retriesSet: true, retryMaxWaitSet: true}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	if want := (service.RetryPolicy{Retries: 0, MaxWait: time.Second}); o.retry != want {
+		t.Errorf("retry with flags = %+v, want %+v", o.retry, want)
+	}
+
+	api.RetryMaxWait = "soon"
+	if _, err := (apiOptions{retry: defaults}).withConfig(api); err == nil {
+		t.Error("withConfig() with an invalid retry_max_wait did not fail")
+	}
+}
+
+func TestAPIOptionsWithConfig_Timeout(t *testing.T) {
+	api := config.API{OpenAPIPath: "/openapi.json", Timeout: "2m"}
+	o, err := apiOptions{timeout: time.Minute}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	if o.timeout != 2*time.Minute {
+		t.Errorf("timeout = %v, want the configured 2m", o.timeout)
+	}
+	o, err = apiOptions{timeout: 5 * time.Second, timeoutSet: true}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	if o.timeout != 5*time.Second {
+		t.Errorf("timeout = %v, want the flag value 5s", o.timeout)
+	}
+}
+
+func TestAPIOptionsWithConfig_Network(t *testing.T) {
+	api := config.API{OpenAPIPath: "/openapi.json", ProxyURL: "http://proxy:3128", NoProxy: "localhost", UnixSocket: "/tmp/api.sock"}
+	o, err := apiOptions{network: service.Network{ProxyURL: "socks5://localhost:1080"}}.withConfig(api)
+	if err != nil {
+		t.Fatalf("withConfig() error = %v", err)
+	}
+	want := service.Network{ProxyURL: "socks5://localhost:1080", NoProxy: "localhost", UnixSocket: "/tmp/api.sock"}
+	if o.network != want {
+		t.Errorf("network = %+v, want %+v", o.network, want)
+	}
+}
+
+func TestNewAuthenticator_Proxy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "proxied", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer proxy.Close()
+
+	o := apiOptions{
+		name:    "bookstore",
+		auth:    &config.Auth{TokenURL: "http://auth.example.com/token", ClientID: "aepcli", ClientSecret: "secret"},
+		network: service.Network{ProxyURL: proxy.URL},
+	}
+	client, err := newHTTPClient(o)
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	a, err := newAuthenticator(o, client)
+	if err != nil {
+		t.Fatalf("newAuthenticator() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+	if err := a.Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer proxied" {
+		t.Errorf("Authorization = %q, want the token fetched through the proxy", got)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://auth.example.com/token" {
+		t.Errorf("proxied = %v, want the token request to be sent through the proxy", proxied)
+	}
+}
+
+func TestNewServiceCommand_ReplayNeedsLocalDefinition(t *testing.T) {
+	_, err := newServiceCommand(apiOptions{openAPIPath: "https://bookstore.example.com/openapi.json", replay: "cassette.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "--replay needs a local OpenAPI definition") {
+		t.Errorf("newServiceCommand() error = %v, want a local definition to be required", err)
+	}
+}
+
+func TestNewServiceCommand_TimingReplay(t *testing.T) {
+	dir := t.TempDir()
+	openAPIPath := filepath.Join(dir, "openapi.json")
+	oas := `{"openapi": "3.1.0", "info": {"title": "bookstore", "version": "1"}, "servers": [{"url": "http://bookstore.example.com"}], "paths": {}}`
+	if err := os.WriteFile(openAPIPath, []byte(oas), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cassettePath := filepath.Join(dir, "cassette.yaml")
+	if err := (&cassette.Cassette{}).Save(cassettePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s, err := newServiceCommand(apiOptions{openAPIPath: openAPIPath, replay: cassettePath, timing: "json"})
+	if err != nil {
+		t.Fatalf("newServiceCommand() error = %v", err)
+	}
+	if s.Timing == nil || s.Client.Transport != http.RoundTripper(s.Timing) {
+		t.Errorf("Client.Transport = %T, want the replayed requests to be timed", s.Client.Transport)
+	}
+}
+
+func TestReadFileOrURL(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		if r.URL.Path != "/openapi.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"openapi": "3.1.0"}`))
+	}))
+	defer proxy.Close()
+	client, err := newHTTPClient(apiOptions{network: service.Network{ProxyURL: proxy.URL}})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+
+	raw, err := readFileOrURL("http://bookstore.example.com/openapi.json", client)
+	if err != nil || string(raw) != `{"openapi": "3.1.0"}` {
+		t.Errorf("readFileOrURL() = %s, %v", raw, err)
+	}
+	if len(proxied) != 1 {
+		t.Errorf("proxied = %v, want the definition to be fetched through the proxy", proxied)
+	}
+	if _, err := readFileOrURL("http://bookstore.example.com/missing.json", client); err == nil || err.Error() != "unable to fetch openapi: status 404" {
+		t.Errorf("readFileOrURL() of a missing definition error = %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "runtime/debug"
+
+// version is the version of aepcli, set by release builds with
+// -ldflags "-X main.version=v1.2.3".
+var version = ""
+
+// buildVersion returns the version of aepcli: the one set at build time, or
+// the version of the module it was installed from with go install.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
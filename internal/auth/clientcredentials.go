@@ -0,0 +1,169 @@
+// Package auth implements the authentication methods aepcli supports for
+// the APIs it calls.
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/config"
+)
+
+// ClientCredentials authenticates requests with access tokens obtained with
+// the OAuth 2.0 client credentials grant. Tokens are cached on disk until
+// they expire, so they can be reused across invocations.
+type ClientCredentials struct {
+	config       config.Auth
+	clientSecret string
+	cache        fileCache
+	// Client is used to call the token endpoint.
+	Client *http.Client
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewClientCredentials returns an authenticator for the given configuration,
+// caching tokens in cacheDir.
+func NewClientCredentials(cfg config.Auth, cacheDir string) (*ClientCredentials, error) {
+	if cfg.TokenURL == "" {
+		return nil, fmt.Errorf("auth: token_url is required")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("auth: client_id is required")
+	}
+	secret, err := clientSecret(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientCredentials{
+		config:       cfg,
+		clientSecret: secret,
+		cache:        fileCache{dir: cacheDir},
+		Client:       http.DefaultClient,
+	}, nil
+}
+
+// clientSecret returns the configured client secret, which may be empty for
+// public clients.
+func clientSecret(cfg config.Auth) (string, error) {
+	if cfg.ClientSecretEnv == "" {
+		return cfg.ClientSecret, nil
+	}
+	secret := os.Getenv(cfg.ClientSecretEnv)
+	if secret == "" {
+		return "", fmt.Errorf("auth: environment variable %s for the client secret is not set", cfg.ClientSecretEnv)
+	}
+	return secret, nil
+}
+
+// Authenticate sets the Authorization header of the request to a valid
+// access token.
+func (c *ClientCredentials) Authenticate(r *http.Request) error {
+	t, err := c.Token()
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+t.AccessToken)
+	return nil
+}
+
+// Token returns a valid access token, from memory, from the cache on disk,
+// or from the token endpoint.
+func (c *ClientCredentials) Token() (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token.valid() {
+		return c.token, nil
+	}
+	key := cacheKey(c.config.TokenURL, c.config.ClientID, strings.Join(c.config.Scopes, " "), c.config.Audience)
+	if t := c.cache.load(key); t.valid() {
+		slog.Debug("Using cached access token", "tokenURL", c.config.TokenURL, "expiry", t.Expiry)
+		c.token = t
+		return t, nil
+	}
+	t, err := c.fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.token = t
+	if !t.Expiry.IsZero() {
+		if err := c.cache.store(key, t); err != nil {
+			slog.Warn("Unable to cache access token", "error", err)
+		}
+	}
+	return t, nil
+}
+
+func (c *ClientCredentials) fetch() (*Token, error) {
+	slog.Debug("Fetching access token", "tokenURL", c.config.TokenURL, "clientID", c.config.ClientID)
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.config.ClientID},
+		"client_secret": {c.clientSecret},
+	}
+	if len(c.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
+	if c.config.Audience != "" {
+		form.Set("audience", c.config.Audience)
+	}
+	return requestToken(c.Client, c.config.TokenURL, form)
+}
+
+// tokenResponse is the response of a token endpoint, as defined in RFC 6749.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts the form to the token endpoint, and returns the token
+// in the response.
+func requestToken(client *http.Client, tokenURL string, form url.Values) (*Token, error) {
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to fetch access token from %s: %v", tokenURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to read token response: %v", err)
+	}
+	var tr tokenResponse
+	jsonErr := json.Unmarshal(body, &tr)
+	if resp.StatusCode/100 != 2 {
+		if jsonErr == nil && tr.Error != "" {
+			return nil, fmt.Errorf("auth: token endpoint returned %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+		}
+		return nil, fmt.Errorf("auth: token endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("auth: unable to parse token response: %v", jsonErr)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("auth: token response from %s has no access_token", tokenURL)
+	}
+	t := &Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, RefreshToken: tr.RefreshToken}
+	if tr.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/config"
+)
+
+// newTokenServer returns a stub token endpoint, issuing tokens that expire
+// after expiresIn seconds, and a counter of the tokens it issued.
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	t.Helper()
+	var issued int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client", "error_description": "bad credentials"})
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d-%s-%s", n, r.PostForm.Get("scope"), r.PostForm.Get("audience")),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s, &issued
+}
+
+func TestClientCredentials(t *testing.T) {
+	s, issued := newTokenServer(t, 3600)
+	cacheDir := t.TempDir()
+	cfg := config.Auth{
+		TokenURL:     s.URL,
+		ClientID:     "aepcli",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+		Audience:     "bookstore",
+	}
+	c, err := NewClientCredentials(cfg, cacheDir)
+	if err != nil {
+		t.Fatalf("NewClientCredentials() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+		if err := c.Authenticate(req); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if got := req.Header.Get("Authorization"); got != "Bearer token-1-read write-bookstore" {
+			t.Errorf("Authorization = %q", got)
+		}
+	}
+
+	// a new authenticator, e.g. in the next invocation, uses the cache.
+	c, err = NewClientCredentials(cfg, cacheDir)
+	if err != nil {
+		t.Fatalf("NewClientCredentials() error = %v", err)
+	}
+	if _, err := c.Token(); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if n := atomic.LoadInt32(issued); n != 1 {
+		t.Errorf("token endpoint called %d times, want 1", n)
+	}
+
+	files, err := os.ReadDir(cacheDir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("cache directory has %d files, error %v", len(files), err)
+	}
+	info, err := os.Stat(filepath.Join(cacheDir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("cached token permissions = %v, want 0600", perm)
+	}
+}
+
+func TestClientCredentials_Expired(t *testing.T) {
+	// tokens expiring within expiryDelta are never reused.
+	s, issued := newTokenServer(t, 1)
+	c, err := NewClientCredentials(config.Auth{TokenURL: s.URL, ClientID: "aepcli", ClientSecret: "secret"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClientCredentials() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Token(); err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(issued); n != 2 {
+		t.Errorf("token endpoint called %d times, want 2", n)
+	}
+}
+
+func TestClientCredentials_Errors(t *testing.T) {
+	s, _ := newTokenServer(t, 3600)
+	t.Setenv("AEPCLI_TEST_SECRET", "wrong")
+
+	tests := []struct {
+		name     string
+		cfg      config.Auth
+		expected string
+	}{
+		{
+			name:     "missing token url",
+			cfg:      config.Auth{ClientID: "aepcli"},
+			expected: "token_url is required",
+		},
+		{
+			name:     "missing secret env",
+			cfg:      config.Auth{TokenURL: s.URL, ClientID: "aepcli", ClientSecretEnv: "AEPCLI_TEST_UNSET"},
+			expected: "environment variable AEPCLI_TEST_UNSET for the client secret is not set",
+		},
+		{
+			name:     "rejected credentials",
+			cfg:      config.Auth{TokenURL: s.URL, ClientID: "aepcli", ClientSecretEnv: "AEPCLI_TEST_SECRET"},
+			expected: "token endpoint returned 401: invalid_client bad credentials",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClientCredentials(tt.cfg, t.TempDir())
+			if err == nil {
+				_, err = c.Token()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenValid(t *testing.T) {
+	if (*Token)(nil).valid() {
+		t.Error("nil token is valid")
+	}
+	if !(&Token{AccessToken: "a"}).valid() {
+		t.Error("token without expiry is not valid")
+	}
+	if (&Token{AccessToken: "a", Expiry: time.Now().Add(time.Second)}).valid() {
+		t.Error("token about to expire is valid")
+	}
+}
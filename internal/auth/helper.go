@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// helperCredential is the JSON a credential helper prints on stdout: a bearer
+// token, headers to set, or both, and optionally when they expire.
+type helperCredential struct {
+	Token   string            `json:"token,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Expiry is an RFC 3339 timestamp. ExpiresIn, in seconds, can be used
+	// instead.
+	Expiry    time.Time `json:"expiry,omitempty"`
+	ExpiresIn int64     `json:"expires_in,omitempty"`
+}
+
+func (c *helperCredential) valid() bool {
+	if c == nil || (c.Token == "" && len(c.Headers) == 0) {
+		return false
+	}
+	return c.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(c.Expiry)
+}
+
+// CredentialHelper authenticates requests with the credentials printed by an
+// external command, like git credential helpers and kubectl exec plugins.
+// Credentials that expire are cached on disk until they do.
+type CredentialHelper struct {
+	command []string
+	cache   fileCache
+	key     string
+
+	mu         sync.Mutex
+	credential *helperCredential
+}
+
+// NewCredentialHelper returns an authenticator running the command, and
+// caching its credentials in cacheDir.
+func NewCredentialHelper(command []string, cacheDir string) (*CredentialHelper, error) {
+	if len(command) == 0 || command[0] == "" {
+		return nil, fmt.Errorf("auth: credential_helper must name a command")
+	}
+	return &CredentialHelper{
+		command: command,
+		cache:   fileCache{dir: cacheDir},
+		key:     cacheKey(append([]string{"credential_helper"}, command...)...),
+	}, nil
+}
+
+// Authenticate sets the token and headers of the helper's credential on the
+// request.
+func (h *CredentialHelper) Authenticate(r *http.Request) error {
+	c, err := h.get()
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	for k, v := range c.Headers {
+		r.Header.Set(k, v)
+	}
+	return nil
+}
+
+// Invalidate discards the cached credential, so the helper is run again for
+// the next request, e.g. after the server rejected the credential.
+func (h *CredentialHelper) Invalidate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.credential = nil
+	h.cache.remove(h.key)
+}
+
+func (h *CredentialHelper) get() (*helperCredential, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.credential.valid() {
+		return h.credential, nil
+	}
+	var cached helperCredential
+	if h.cache.read(h.key, &cached) && cached.valid() {
+		slog.Debug("Using cached helper credential", "expiry", cached.Expiry)
+		h.credential = &cached
+		return &cached, nil
+	}
+	c, err := h.run()
+	if err != nil {
+		return nil, err
+	}
+	h.credential = c
+	if !c.Expiry.IsZero() {
+		if err := h.cache.write(h.key, c); err != nil {
+			slog.Warn("Unable to cache helper credential", "error", err)
+		}
+	}
+	return c, nil
+}
+
+// run runs the helper. Its stderr and stdin are the user's, so it can ask
+// them to log in.
+func (h *CredentialHelper) run() (*helperCredential, error) {
+	slog.Debug("Running credential helper", "command", h.command)
+	var stdout bytes.Buffer
+	cmd := exec.Command(h.command[0], h.command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("auth: credential helper %q failed: %v", strings.Join(h.command, " "), err)
+	}
+	var c helperCredential
+	if err := json.Unmarshal(stdout.Bytes(), &c); err != nil {
+		return nil, fmt.Errorf("auth: unable to parse the output of credential helper %q: %v", h.command[0], err)
+	}
+	if c.Token == "" && len(c.Headers) == 0 {
+		return nil, fmt.Errorf("auth: credential helper %q returned neither a token nor headers", h.command[0])
+	}
+	if c.Expiry.IsZero() && c.ExpiresIn > 0 {
+		c.Expiry = time.Now().Add(time.Duration(c.ExpiresIn) * time.Second)
+	}
+	c.ExpiresIn = 0
+	return &c, nil
+}
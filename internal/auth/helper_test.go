@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is run as a credential helper by the tests. It prints its
+// first argument, and records that it ran in the file named by the second.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("AEPCLI_TEST_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	f, _ := os.OpenFile(args[2], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	f.WriteString("ran\n")
+	f.Close()
+	fmt.Print(args[1])
+	os.Exit(0)
+}
+
+// helperCommand returns a credential helper printing output, and a function
+// returning how many times it ran.
+func helperCommand(t *testing.T, output string) ([]string, func() int) {
+	t.Helper()
+	t.Setenv("AEPCLI_TEST_HELPER", "1")
+	runs := filepath.Join(t.TempDir(), "runs")
+	command := []string{os.Args[0], "-test.run=TestHelperProcess", "--", output, runs}
+	return command, func() int {
+		b, _ := os.ReadFile(runs)
+		return strings.Count(string(b), "ran")
+	}
+}
+
+func TestCredentialHelper(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	command, runs := helperCommand(t, `{"token": "helper-token", "headers": {"x-tenant": "acme"}, "expiry": "`+expiry+`"}`)
+	cacheDir := t.TempDir()
+
+	h, err := NewCredentialHelper(command, cacheDir)
+	if err != nil {
+		t.Fatalf("NewCredentialHelper() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+		if err := h.Authenticate(req); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if got := req.Header.Get("Authorization"); got != "Bearer helper-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := req.Header.Get("x-tenant"); got != "acme" {
+			t.Errorf("x-tenant = %q", got)
+		}
+	}
+	if runs() != 1 {
+		t.Errorf("helper ran %d times, want 1", runs())
+	}
+
+	// the credential is cached across invocations until it expires.
+	h, _ = NewCredentialHelper(command, cacheDir)
+	if err := h.Authenticate(httptest.NewRequest(http.MethodGet, "http://bookstore.example.com", nil)); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if runs() != 1 {
+		t.Errorf("helper ran %d times with a cached credential, want 1", runs())
+	}
+
+	h.Invalidate()
+	if err := h.Authenticate(httptest.NewRequest(http.MethodGet, "http://bookstore.example.com", nil)); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if runs() != 2 {
+		t.Errorf("helper ran %d times after Invalidate(), want 2", runs())
+	}
+}
+
+func TestCredentialHelper_Errors(t *testing.T) {
+	if _, err := NewCredentialHelper(nil, ""); err == nil {
+		t.Error("NewCredentialHelper() without a command did not fail")
+	}
+	for output, want := range map[string]string{
+		"not json": "unable to parse the output",
+		"{}":       "neither a token nor headers",
+	} {
+		command, _ := helperCommand(t, output)
+		h, _ := NewCredentialHelper(command, "")
+		err := h.Authenticate(httptest.NewRequest(http.MethodGet, "http://bookstore.example.com", nil))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Authenticate() with output %q error = %v, want %q", output, err, want)
+		}
+	}
+	h, _ := NewCredentialHelper([]string{filepath.Join(t.TempDir(), "missing")}, "")
+	if err := h.Authenticate(httptest.NewRequest(http.MethodGet, "http://bookstore.example.com", nil)); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("Authenticate() with a missing helper error = %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/config"
+)
+
+// loginTimeout is how long Login waits for the user to complete the login
+// in the browser.
+const loginTimeout = 5 * time.Minute
+
+// CredentialsDir returns the directory the tokens of logged in users are
+// stored in.
+func CredentialsDir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials"), nil
+}
+
+// Login runs the OAuth 2.0 authorization code flow with PKCE (RFC 7636) for
+// the named API, and stores the resulting tokens in dir. The user is sent to
+// the authorization URL with open, and redirected back to a temporary
+// listener on the loopback interface (RFC 8252). The token endpoint is
+// called with client.
+func Login(cfg config.Auth, dir, name string, client *http.Client, open func(url string) error) error {
+	if cfg.AuthorizationURL == "" || cfg.TokenURL == "" || cfg.ClientID == "" {
+		return fmt.Errorf("auth: authorization_url, token_url and client_id are required to log in")
+	}
+	secret, err := clientSecret(cfg)
+	if err != nil {
+		return err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	state, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", cfg.RedirectPort))
+	if err != nil {
+		return fmt.Errorf("auth: unable to listen for the login redirect: %v", err)
+	}
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	type callback struct {
+		code string
+		err  error
+	}
+	result := make(chan callback, 1)
+	var once sync.Once
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var cb callback
+		switch {
+		case q.Get("state") != state:
+			cb.err = fmt.Errorf("auth: login redirect has an invalid state")
+		case q.Get("error") != "":
+			cb.err = fmt.Errorf("auth: login failed: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			cb.err = fmt.Errorf("auth: login redirect has no code")
+		default:
+			cb.code = q.Get("code")
+		}
+		if cb.err != nil {
+			http.Error(w, cb.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login complete. You can close this window and return to aepcli.")
+		}
+		once.Do(func() { result <- cb })
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if len(cfg.Scopes) > 0 {
+		q.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	if cfg.Audience != "" {
+		q.Set("audience", cfg.Audience)
+	}
+	authURL := cfg.AuthorizationURL + "?" + q.Encode()
+	if strings.Contains(cfg.AuthorizationURL, "?") {
+		authURL = cfg.AuthorizationURL + "&" + q.Encode()
+	}
+	if err := open(authURL); err != nil {
+		return err
+	}
+
+	var cb callback
+	select {
+	case cb = <-result:
+	case <-time.After(loginTimeout):
+		return fmt.Errorf("auth: timed out waiting for the login to complete")
+	}
+	if cb.err != nil {
+		return cb.err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {cb.code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if secret != "" {
+		form.Set("client_secret", secret)
+	}
+	t, err := requestToken(client, cfg.TokenURL, form)
+	if err != nil {
+		return err
+	}
+	return fileCache{dir: dir}.store(credentialsFile(name), t)
+}
+
+// Logout removes the stored tokens of the named API. It is not an error if
+// the user was not logged in.
+func Logout(dir, name string) error {
+	err := os.Remove(filepath.Join(dir, credentialsFile(name)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("auth: unable to remove credentials: %v", err)
+	}
+	return nil
+}
+
+// StoredToken returns the stored token of the named API, or nil if the user
+// is not logged in.
+func StoredToken(dir, name string) *Token {
+	return fileCache{dir: dir}.load(credentialsFile(name))
+}
+
+// OpenBrowser prints the URL, and tries to open it in the default browser.
+func OpenBrowser(u string) error {
+	fmt.Fprintf(os.Stderr, "Opening the login page in your browser. If it does not open, visit:\n\n  %s\n\n", u)
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := cmd.Start(); err != nil {
+		slog.Debug("Unable to open browser", "error", err)
+	}
+	return nil
+}
+
+func credentialsFile(name string) string {
+	return name + ".json"
+}
+
+// randomString returns a URL-safe string of 32 random bytes, suitable for
+// PKCE code verifiers and state parameters.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("auth: unable to generate random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthorizationCode authenticates requests with the access token of a user
+// who logged in with Login, refreshing it when it expires.
+type AuthorizationCode struct {
+	config       config.Auth
+	clientSecret string
+	name         string
+	store        fileCache
+	// Client is used to call the token endpoint.
+	Client *http.Client
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewAuthorizationCode returns an authenticator using the tokens stored in
+// dir for the named API.
+func NewAuthorizationCode(cfg config.Auth, dir, name string) (*AuthorizationCode, error) {
+	secret, err := clientSecret(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorizationCode{
+		config:       cfg,
+		clientSecret: secret,
+		name:         name,
+		store:        fileCache{dir: dir},
+		Client:       http.DefaultClient,
+	}, nil
+}
+
+// Authenticate sets the Authorization header of the request to a valid
+// access token.
+func (a *AuthorizationCode) Authenticate(r *http.Request) error {
+	t, err := a.Token()
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+t.AccessToken)
+	return nil
+}
+
+// Token returns a valid access token, refreshing the stored one if it has
+// expired.
+func (a *AuthorizationCode) Token() (*Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token.valid() {
+		return a.token, nil
+	}
+	t := a.store.load(credentialsFile(a.name))
+	if t == nil {
+		return nil, fmt.Errorf("auth: not logged in, run: aepcli core auth login %s", a.name)
+	}
+	if t.valid() {
+		a.token = t
+		return t, nil
+	}
+	if t.RefreshToken == "" {
+		return nil, fmt.Errorf("auth: the session has expired, run: aepcli core auth login %s", a.name)
+	}
+	slog.Debug("Refreshing access token", "tokenURL", a.config.TokenURL)
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {t.RefreshToken},
+		"client_id":     {a.config.ClientID},
+	}
+	if a.clientSecret != "" {
+		form.Set("client_secret", a.clientSecret)
+	}
+	refreshed, err := requestToken(a.Client, a.config.TokenURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("%v\nrun: aepcli core auth login %s", err, a.name)
+	}
+	// refresh tokens are only rotated by some servers.
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = t.RefreshToken
+	}
+	if err := a.store.store(credentialsFile(a.name), refreshed); err != nil {
+		slog.Warn("Unable to store refreshed access token", "error", err)
+	}
+	a.token = refreshed
+	return refreshed, nil
+}
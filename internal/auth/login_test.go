@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aep-dev/aepcli/internal/config"
+)
+
+// authServer is a stub OAuth 2.0 server implementing the authorization code
+// grant with PKCE, and the refresh token grant.
+type authServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	challenge string
+	grants    []string
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	t.Helper()
+	a := &authServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "aepcli" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		a.mu.Lock()
+		a.challenge = q.Get("code_challenge")
+		a.mu.Unlock()
+		redirect := fmt.Sprintf("%s?code=the-code&state=%s", q.Get("redirect_uri"), url.QueryEscape(q.Get("state")))
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		grant := r.PostForm.Get("grant_type")
+		a.grants = append(a.grants, grant)
+		w.Header().Set("Content-Type", "application/json")
+		switch grant {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if r.PostForm.Get("code") != "the-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != a.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			// the access token expires immediately, to be refreshed.
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "first", "refresh_token": "refresh", "expires_in": 1,
+			})
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "refreshed", "expires_in": 3600})
+		}
+	})
+	a.Server = httptest.NewServer(mux)
+	t.Cleanup(a.Close)
+	return a
+}
+
+// browser follows the login page, and its redirect back to aepcli.
+func browser(u string) error {
+	go func() {
+		resp, err := http.Get(u)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}()
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	s := newAuthServer(t)
+	dir := t.TempDir()
+	cfg := config.Auth{
+		AuthorizationURL: s.URL + "/authorize",
+		TokenURL:         s.URL + "/token",
+		ClientID:         "aepcli",
+		Scopes:           []string{"openid"},
+	}
+
+	if err := Login(cfg, dir, "bookstore", http.DefaultClient, browser); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "bookstore.json"))
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials permissions = %v, want 0600", perm)
+	}
+	if tok := StoredToken(dir, "bookstore"); tok == nil || tok.RefreshToken != "refresh" {
+		t.Errorf("StoredToken() = %+v", tok)
+	}
+
+	a, err := NewAuthorizationCode(cfg, dir, "bookstore")
+	if err != nil {
+		t.Fatalf("NewAuthorizationCode() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+	if err := a.Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer refreshed" {
+		t.Errorf("Authorization = %q, want the refreshed token", got)
+	}
+	// the refresh token is kept if the server does not rotate it.
+	if tok := StoredToken(dir, "bookstore"); tok == nil || tok.AccessToken != "refreshed" || tok.RefreshToken != "refresh" {
+		t.Errorf("StoredToken() after refresh = %+v", tok)
+	}
+	if want := []string{"authorization_code", "refresh_token"}; strings.Join(s.grants, ",") != strings.Join(want, ",") {
+		t.Errorf("grants = %v, want %v", s.grants, want)
+	}
+
+	if err := Logout(dir, "bookstore"); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if err := Logout(dir, "bookstore"); err != nil {
+		t.Errorf("Logout() when logged out error = %v", err)
+	}
+	a, _ = NewAuthorizationCode(cfg, dir, "bookstore")
+	if _, err := a.Token(); err == nil || !strings.Contains(err.Error(), "not logged in, run: aepcli core auth login bookstore") {
+		t.Errorf("Token() after logout error = %v", err)
+	}
+}
+
+func TestLogin_Denied(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Auth{AuthorizationURL: "http://auth.example.com/authorize", TokenURL: "http://auth.example.com/token", ClientID: "aepcli"}
+	deny := func(u string) error {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		q := parsed.Query()
+		return browser(fmt.Sprintf("%s?error=access_denied&state=%s", q.Get("redirect_uri"), url.QueryEscape(q.Get("state"))))
+	}
+	if err := Login(cfg, dir, "bookstore", http.DefaultClient, deny); err == nil || !strings.Contains(err.Error(), "login failed: access_denied") {
+		t.Errorf("Login() error = %v", err)
+	}
+	if StoredToken(dir, "bookstore") != nil {
+		t.Error("credentials were stored for a failed login")
+	}
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aep-dev/aepcli/internal/secret"
+	"golang.org/x/term"
+)
+
+// errNotInteractive is returned when a credential has to be prompted for, but
+// there is no terminal to ask on.
+var errNotInteractive = errors.New("stdin is not a terminal")
+
+// Schemes authenticates requests with the security schemes an API declares
+// in its OpenAPI definition. The credential of each scheme is read from an
+// environment variable, from the credentials of the API configuration, or
+// prompted for, in that order.
+type Schemes struct {
+	security    *Security
+	api         string
+	credentials map[string]string
+	// Prompt asks the user for a credential. It defaults to reading it from
+	// the terminal without echoing it.
+	Prompt func(question string) (string, error)
+
+	mu       sync.Mutex
+	resolved map[string]string
+}
+
+// NewSchemes returns an authenticator for the security of the named API, with
+// the credentials configured for it, keyed by scheme name.
+func NewSchemes(security *Security, api string, credentials map[string]string) *Schemes {
+	return &Schemes{
+		security:    security,
+		api:         api,
+		credentials: credentials,
+		Prompt:      promptSecret,
+		resolved:    map[string]string{},
+	}
+}
+
+// EnvVar returns the environment variable the credential of the scheme is
+// read from, e.g. AEPCLI_BOOKSTORE_APIKEY for the ApiKey scheme of the
+// bookstore API.
+func (a *Schemes) EnvVar(scheme string) string {
+	parts := []string{"AEPCLI"}
+	if a.api != "" {
+		parts = append(parts, envName(a.api))
+	}
+	return strings.Join(append(parts, envName(scheme)), "_")
+}
+
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// Authenticate adds credentials to the request, as required by the
+// top-level security of the API.
+func (a *Schemes) Authenticate(r *http.Request) error {
+	return a.AuthenticateOperation(r, "")
+}
+
+// AuthenticateOperation adds credentials to the request for the operation
+// at the path of the OpenAPI definition, e.g. /publishers/{publisher}, as
+// required by its security, or the top-level one. The credentials of the
+// first requirement that can be satisfied without asking are added. If none
+// can, and authentication is not optional, the credentials of the first
+// requirement are prompted for.
+func (a *Schemes) AuthenticateOperation(r *http.Request, path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	requirements := a.security.requirements(r.Method, path)
+	required := len(requirements) > 0
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			required = false
+			continue
+		}
+		values, ok := a.lookup(r, requirement)
+		if ok {
+			return a.apply(r, requirement, values)
+		}
+	}
+	if !required {
+		return nil
+	}
+	requirement := requirements[0]
+	values := map[string]string{}
+	for _, name := range requirement {
+		if a.provided(r, name) {
+			continue
+		}
+		v, ok := a.value(name)
+		if !ok {
+			var err error
+			v, err = a.Prompt(fmt.Sprintf("Enter the %s for %s: ", a.security.Schemes[name].describe(), name))
+			if errors.Is(err, errNotInteractive) {
+				return fmt.Errorf("auth: no credential for the %s security scheme: set %s, or credentials.%s in the API configuration", name, a.EnvVar(name), name)
+			}
+			if err != nil {
+				return err
+			}
+			a.resolved[name] = v
+		}
+		values[name] = v
+	}
+	return a.apply(r, requirement, values)
+}
+
+// lookup returns the credentials of the schemes of the requirement, if they
+// are all available without prompting.
+func (a *Schemes) lookup(r *http.Request, requirement []string) (map[string]string, bool) {
+	values := map[string]string{}
+	for _, name := range requirement {
+		if a.provided(r, name) {
+			continue
+		}
+		v, ok := a.value(name)
+		if !ok {
+			return nil, false
+		}
+		values[name] = v
+	}
+	return values, true
+}
+
+// value returns the credential of the scheme from the environment, the
+// configuration, or an earlier prompt.
+func (a *Schemes) value(name string) (string, bool) {
+	if v := os.Getenv(a.EnvVar(name)); v != "" {
+		return v, true
+	}
+	if v, ok := a.credentials[name]; ok && v != "" {
+		resolved, err := secret.Resolve(v)
+		if err != nil {
+			slog.Warn("Unable to resolve credential", "scheme", name, "error", err)
+			return "", false
+		}
+		return resolved, true
+	}
+	v, ok := a.resolved[name]
+	return v, ok
+}
+
+// provided returns whether the request already carries the credential of the
+// scheme, e.g. from a header set with --header.
+func (a *Schemes) provided(r *http.Request, name string) bool {
+	s := a.security.Schemes[name]
+	if s.Type == "apiKey" && (s.In == "" || s.In == "header") {
+		return r.Header.Get(s.ParamName) != ""
+	}
+	if s.Type == "http" || s.Type == "oauth2" || s.Type == "openIdConnect" {
+		return r.Header.Get("Authorization") != ""
+	}
+	return false
+}
+
+func (a *Schemes) apply(r *http.Request, requirement []string, values map[string]string) error {
+	for _, name := range requirement {
+		v, ok := values[name]
+		if !ok {
+			continue
+		}
+		s := a.security.Schemes[name]
+		switch s.Type {
+		case "apiKey":
+			switch s.In {
+			case "query":
+				q := r.URL.Query()
+				q.Set(s.ParamName, v)
+				r.URL.RawQuery = q.Encode()
+			case "cookie":
+				r.AddCookie(&http.Cookie{Name: s.ParamName, Value: v})
+			default:
+				r.Header.Set(s.ParamName, v)
+			}
+		case "http":
+			switch strings.ToLower(s.Scheme) {
+			case "basic":
+				user, password, ok := strings.Cut(v, ":")
+				if !ok {
+					return fmt.Errorf("auth: the credential for the %s security scheme must be user:password", name)
+				}
+				r.SetBasicAuth(user, password)
+			case "bearer":
+				r.Header.Set("Authorization", "Bearer "+v)
+			default:
+				r.Header.Set("Authorization", s.Scheme+" "+v)
+			}
+		case "oauth2", "openIdConnect":
+			r.Header.Set("Authorization", "Bearer "+v)
+		default:
+			return fmt.Errorf("auth: security scheme %s has unsupported type %q", name, s.Type)
+		}
+	}
+	return nil
+}
+
+// Help describes the credentials the API requires, and how to provide them.
+func (a *Schemes) Help() string {
+	var b strings.Builder
+	requirements := a.security.requirements("", "")
+	alternatives := []string{}
+	optional := len(requirements) == 0
+	for _, requirement := range requirements {
+		if len(requirement) > 0 {
+			alternatives = append(alternatives, strings.Join(requirement, " and "))
+		} else {
+			optional = true
+		}
+	}
+	if optional {
+		alternatives = append(alternatives, "none")
+	}
+	fmt.Fprintf(&b, "Authentication (one of: %s):\n", strings.Join(alternatives, ", "))
+	printed := map[string]bool{}
+	for _, requirement := range requirements {
+		for _, name := range requirement {
+			if printed[name] {
+				continue
+			}
+			printed[name] = true
+			fmt.Fprintf(&b, "  %s: %s\n", name, a.security.Schemes[name].describe())
+			fmt.Fprintf(&b, "    set $%s, or credentials.%s in the API configuration, or enter it when prompted\n", a.EnvVar(name), name)
+		}
+	}
+	return b.String()
+}
+
+// promptSecret asks the question on stderr, and reads the answer from the
+// terminal without echoing it.
+func promptSecret(question string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errNotInteractive
+	}
+	fmt.Fprint(os.Stderr, question)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("auth: unable to read credential: %v", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const securitySpec = `{
+	"openapi": "3.1.0",
+	"components": {
+		"securitySchemes": {
+			"ApiKey": {"type": "apiKey", "in": "header", "name": "x-api-key"},
+			"QueryKey": {"type": "apiKey", "in": "query", "name": "key"},
+			"Session": {"type": "apiKey", "in": "cookie", "name": "session"},
+			"Basic": {"type": "http", "scheme": "basic"},
+			"Bearer": {"type": "http", "scheme": "bearer"},
+			"OAuth": {"type": "oauth2", "flows": {"clientCredentials": {"tokenUrl": "https://auth.example.com/token"}}}
+		}
+	},
+	"security": [{"ApiKey": []}, {"QueryKey": [], "Session": []}, {"Basic": []}, {"Bearer": []}, {"OAuth": []}]
+}`
+
+func TestParseSecurity(t *testing.T) {
+	sec, err := ParseSecurity([]byte(securitySpec))
+	if err != nil {
+		t.Fatalf("ParseSecurity() error = %v", err)
+	}
+	if len(sec.Schemes) != 6 || len(sec.Requirements) != 5 {
+		t.Fatalf("ParseSecurity() = %d schemes, %d requirements", len(sec.Schemes), len(sec.Requirements))
+	}
+	if got := strings.Join(sec.Requirements[1], ","); got != "QueryKey,Session" {
+		t.Errorf("Requirements[1] = %q", got)
+	}
+
+	// OpenAPI 2.0 definitions, without top-level requirements.
+	sec, err = ParseSecurity([]byte(`{"swagger": "2.0", "securityDefinitions": {"b": {"type": "basic"}, "a": {"type": "apiKey", "in": "header", "name": "k"}}}`))
+	if err != nil {
+		t.Fatalf("ParseSecurity() error = %v", err)
+	}
+	if sec.Schemes["b"].Type != "http" || sec.Schemes["b"].Scheme != "basic" {
+		t.Errorf("basic scheme = %+v", sec.Schemes["b"])
+	}
+	if sec.Requirements != nil {
+		t.Errorf("Requirements = %v, want none", sec.Requirements)
+	}
+	if got := sec.requirements(http.MethodGet, ""); len(got) != 3 || got[0][0] != "a" || len(got[2]) != 0 {
+		t.Errorf("requirements() = %v, want each scheme, optionally", got)
+	}
+
+	if sec, err := ParseSecurity([]byte(`{"openapi": "3.1.0"}`)); sec != nil || err != nil {
+		t.Errorf("ParseSecurity() without schemes = %v, %v", sec, err)
+	}
+	if _, err := ParseSecurity([]byte(`{"components": {"securitySchemes": {"a": {"type": "http"}}}, "security": [{"b": []}]}`)); err == nil {
+		t.Error("ParseSecurity() with an unknown scheme did not fail")
+	}
+}
+
+func TestSchemes_Authenticate(t *testing.T) {
+	sec, err := ParseSecurity([]byte(securitySpec))
+	if err != nil {
+		t.Fatalf("ParseSecurity() error = %v", err)
+	}
+	tests := []struct {
+		name        string
+		env         map[string]string
+		credentials map[string]string
+		header      string
+		check       func(*http.Request) bool
+	}{
+		{
+			name:  "api key from the environment",
+			env:   map[string]string{"AEPCLI_BOOKSTORE_APIKEY": "env-key"},
+			check: func(r *http.Request) bool { return r.Header.Get("x-api-key") == "env-key" },
+		},
+		{
+			name:        "query and cookie from the configuration",
+			credentials: map[string]string{"QueryKey": "q", "Session": "s"},
+			check: func(r *http.Request) bool {
+				c, err := r.Cookie("session")
+				return r.URL.Query().Get("key") == "q" && err == nil && c.Value == "s"
+			},
+		},
+		{
+			name:        "basic",
+			credentials: map[string]string{"Basic": "alice:secret"},
+			check: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "alice" && p == "secret"
+			},
+		},
+		{
+			name:        "oauth2 access token",
+			credentials: map[string]string{"OAuth": "token"},
+			check:       func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer token" },
+		},
+		{
+			name:   "header set by the user",
+			header: "user-key",
+			check:  func(r *http.Request) bool { return r.Header.Get("x-api-key") == "user-key" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			a := NewSchemes(sec, "bookstore", tt.credentials)
+			a.Prompt = func(string) (string, error) {
+				t.Fatal("unexpected prompt")
+				return "", nil
+			}
+			r := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+			if tt.header != "" {
+				r.Header.Set("x-api-key", tt.header)
+			}
+			if err := a.Authenticate(r); err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if !tt.check(r) {
+				t.Errorf("request not authenticated: %v %v", r.URL, r.Header)
+			}
+		})
+	}
+}
+
+func TestSchemes_Prompt(t *testing.T) {
+	sec, err := ParseSecurity([]byte(securitySpec))
+	if err != nil {
+		t.Fatalf("ParseSecurity() error = %v", err)
+	}
+	a := NewSchemes(sec, "bookstore", nil)
+	prompts := 0
+	a.Prompt = func(q string) (string, error) {
+		prompts++
+		if !strings.Contains(q, `API key in the "x-api-key" header for ApiKey`) {
+			t.Errorf("prompt = %q", q)
+		}
+		return "typed", nil
+	}
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+		if err := a.Authenticate(r); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if got := r.Header.Get("x-api-key"); got != "typed" {
+			t.Errorf("x-api-key = %q, want the prompted key", got)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("prompted %d times, want 1", prompts)
+	}
+
+	a = NewSchemes(sec, "bookstore", nil)
+	a.Prompt = func(string) (string, error) { return "", errNotInteractive }
+	r := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+	if err := a.Authenticate(r); err == nil || !strings.Contains(err.Error(), "set AEPCLI_BOOKSTORE_APIKEY, or credentials.ApiKey") {
+		t.Errorf("Authenticate() without a terminal error = %v", err)
+	}
+}
+
+func TestSchemes_AuthenticateOperation(t *testing.T) {
+	sec, err := ParseSecurity([]byte(`{
+		"openapi": "3.1.0",
+		"components": {"securitySchemes": {"ApiKey": {"type": "apiKey", "in": "header", "name": "x-api-key"}}},
+		"security": [{"ApiKey": []}],
+		"paths": {
+			"/publishers": {
+				"get": {"security": []},
+				"post": {}
+			},
+			"/publishers/{publisher}": {
+				"parameters": [],
+				"get": {"security": [{}, {"ApiKey": []}]}
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSecurity() error = %v", err)
+	}
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		env        string
+		wantKey    string
+		wantPrompt bool
+	}{
+		{"public operation", http.MethodGet, "/publishers", "", "", false},
+		{"public operation with a credential", http.MethodGet, "/publishers", "env-key", "", false},
+		{"top-level requirement", http.MethodPost, "/publishers", "", "", true},
+		{"unknown operation", http.MethodDelete, "", "", "", true},
+		{"optional without a credential", http.MethodGet, "/publishers/{publisher}", "", "", false},
+		{"optional with a credential", http.MethodGet, "/publishers/{publisher}", "env-key", "env-key", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AEPCLI_BOOKSTORE_APIKEY", tt.env)
+			a := NewSchemes(sec, "bookstore", nil)
+			prompted := false
+			a.Prompt = func(string) (string, error) {
+				prompted = true
+				return "", errNotInteractive
+			}
+			r := httptest.NewRequest(tt.method, "http://bookstore.example.com/publishers", nil)
+			err := a.AuthenticateOperation(r, tt.path)
+			if prompted != tt.wantPrompt {
+				t.Errorf("prompted = %v, want %v", prompted, tt.wantPrompt)
+			}
+			if tt.wantPrompt && (err == nil || !strings.Contains(err.Error(), "no credential for the ApiKey security scheme")) {
+				t.Errorf("AuthenticateOperation() error = %v, want the credential to be required", err)
+			}
+			if !tt.wantPrompt && err != nil {
+				t.Errorf("AuthenticateOperation() error = %v", err)
+			}
+			if got := r.Header.Get("x-api-key"); got != tt.wantKey {
+				t.Errorf("x-api-key = %q, want %q", got, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestSchemes_NoRequirement(t *testing.T) {
+	for _, spec := range []string{
+		`{"components": {"securitySchemes": {"ApiKey": {"type": "apiKey", "in": "header", "name": "x-api-key"}}}}`,
+		`{"components": {"securitySchemes": {"ApiKey": {"type": "apiKey", "in": "header", "name": "x-api-key"}}}, "security": []}`,
+	} {
+		sec, err := ParseSecurity([]byte(spec))
+		if err != nil {
+			t.Fatalf("ParseSecurity() error = %v", err)
+		}
+		a := NewSchemes(sec, "bookstore", nil)
+		a.Prompt = func(string) (string, error) {
+			t.Errorf("unexpected prompt for %s", spec)
+			return "", errNotInteractive
+		}
+		r := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+		if err := a.Authenticate(r); err != nil {
+			t.Errorf("Authenticate() error = %v, want no credential to be required by %s", err, spec)
+		}
+	}
+
+	// declared schemes are still used if their credential is available.
+	sec, _ := ParseSecurity([]byte(`{"components": {"securitySchemes": {"ApiKey": {"type": "apiKey", "in": "header", "name": "x-api-key"}}}}`))
+	a := NewSchemes(sec, "bookstore", map[string]string{"ApiKey": "configured"})
+	r := httptest.NewRequest(http.MethodGet, "http://bookstore.example.com/publishers", nil)
+	if err := a.Authenticate(r); err != nil || r.Header.Get("x-api-key") != "configured" {
+		t.Errorf("Authenticate() = %v, x-api-key %q, want the configured key", err, r.Header.Get("x-api-key"))
+	}
+	if help := a.Help(); !strings.Contains(help, "Authentication (one of: ApiKey, none):") {
+		t.Errorf("Help() = %s, want authentication to be optional", help)
+	}
+}
+
+func TestSchemes_Help(t *testing.T) {
+	sec, err := ParseSecurity([]byte(securitySpec))
+	if err != nil {
+		t.Fatalf("ParseSecurity() error = %v", err)
+	}
+	help := NewSchemes(sec, "bookstore", nil).Help()
+	for _, want := range []string{
+		"Authentication (one of: ApiKey, QueryKey and Session, Basic, Bearer, OAuth):",
+		`  ApiKey: API key in the "x-api-key" header`,
+		"    set $AEPCLI_BOOKSTORE_APIKEY, or credentials.ApiKey in the API configuration",
+		`  QueryKey: API key in the "key" query parameter`,
+		"  Basic: user name and password, as user:password",
+		`  OAuth: OAuth 2.0 access token in the Authorization header, or configure auth with token_url = "https://auth.example.com/token"`,
+	} {
+		if !strings.Contains(help, want) {
+			t.Errorf("Help() does not contain %q:\n%s", want, help)
+		}
+	}
+}
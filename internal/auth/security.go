@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecurityScheme is a security scheme declared by an OpenAPI definition.
+type SecurityScheme struct {
+	// Name is the key of the scheme in the definition.
+	Name string `json:"-"`
+	// Type is apiKey, http, oauth2 or openIdConnect. The OpenAPI 2.0 basic
+	// type is converted to http with the basic scheme.
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// In and ParamName locate the API key: the header, query parameter or
+	// cookie it is sent in.
+	In        string `json:"in,omitempty"`
+	ParamName string `json:"name,omitempty"`
+	// Scheme is the HTTP authentication scheme, e.g. bearer or basic.
+	Scheme string      `json:"scheme,omitempty"`
+	Flows  *OAuthFlows `json:"flows,omitempty"`
+	// TokenURL is set by OpenAPI 2.0 oauth2 schemes.
+	TokenURL string `json:"tokenUrl,omitempty"`
+}
+
+// OAuthFlows are the OAuth 2.0 flows supported by an oauth2 scheme.
+type OAuthFlows struct {
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+// OAuthFlow is the endpoints of an OAuth 2.0 flow.
+type OAuthFlow struct {
+	AuthorizationURL string `json:"authorizationUrl,omitempty"`
+	TokenURL         string `json:"tokenUrl,omitempty"`
+}
+
+// Security is the authentication an API declares: its schemes, and the
+// alternative combinations of schemes requests must satisfy.
+type Security struct {
+	Schemes map[string]*SecurityScheme
+	// Requirements are the top-level alternatives: a request is
+	// authenticated if it satisfies every scheme of one of them. An empty
+	// alternative, or no alternatives, means authentication is optional.
+	// Requirements is nil if the definition declares none.
+	Requirements [][]string
+	// Operations are the requirements of the operations that declare their
+	// own, by path and lower-case method. They replace the top-level ones.
+	Operations map[string]map[string][][]string
+}
+
+// operationMethods are the keys of the operations of an OpenAPI path item.
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ParseSecurity reads the security schemes, and the top-level and operation
+// requirements, of a raw OpenAPI 2.0 or 3.x definition. It returns nil if the
+// definition declares no security schemes.
+func ParseSecurity(raw []byte) (*Security, error) {
+	var doc struct {
+		Components struct {
+			SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+		} `json:"components"`
+		SecurityDefinitions map[string]*SecurityScheme            `json:"securityDefinitions"`
+		Security            *[]map[string][]string                `json:"security"`
+		Paths               map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse security schemes: %v", err)
+	}
+	schemes := doc.Components.SecuritySchemes
+	if len(schemes) == 0 {
+		schemes = doc.SecurityDefinitions
+	}
+	if len(schemes) == 0 {
+		return nil, nil
+	}
+	for name, s := range schemes {
+		s.Name = name
+		if s.Type == "basic" {
+			s.Type, s.Scheme = "http", "basic"
+		}
+	}
+
+	sec := &Security{Schemes: schemes, Operations: map[string]map[string][][]string{}}
+	var err error
+	if doc.Security != nil {
+		if sec.Requirements, err = parseRequirements(*doc.Security, schemes); err != nil {
+			return nil, err
+		}
+	}
+	for path, item := range doc.Paths {
+		for _, method := range operationMethods {
+			rawOp, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op struct {
+				Security *[]map[string][]string `json:"security"`
+			}
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("unable to parse the security of %s %s: %v", strings.ToUpper(method), path, err)
+			}
+			if op.Security == nil {
+				continue
+			}
+			requirements, err := parseRequirements(*op.Security, schemes)
+			if err != nil {
+				return nil, err
+			}
+			if sec.Operations[path] == nil {
+				sec.Operations[path] = map[string][][]string{}
+			}
+			sec.Operations[path][method] = requirements
+		}
+	}
+	return sec, nil
+}
+
+// parseRequirements returns the scheme names of each alternative of a
+// security requirement. It is not nil, even if there are no alternatives.
+func parseRequirements(alternatives []map[string][]string, schemes map[string]*SecurityScheme) ([][]string, error) {
+	requirements := [][]string{}
+	for _, alternative := range alternatives {
+		names := []string{}
+		for name := range alternative {
+			if _, ok := schemes[name]; !ok {
+				return nil, fmt.Errorf("security requirement refers to unknown scheme %q", name)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		requirements = append(requirements, names)
+	}
+	return requirements, nil
+}
+
+// requirements returns the alternatives of the operation of the method on
+// the path, which may be empty if it is not known: its own, or the top-level
+// ones. Without either, any one of the schemes is used if its credential is
+// available, and authentication is optional.
+func (s *Security) requirements(method, path string) [][]string {
+	if r, ok := s.Operations[path][strings.ToLower(method)]; ok {
+		return r
+	}
+	if s.Requirements != nil {
+		return s.Requirements
+	}
+	names := make([]string, 0, len(s.Schemes))
+	for name := range s.Schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	requirements := [][]string{}
+	for _, name := range names {
+		requirements = append(requirements, []string{name})
+	}
+	return append(requirements, []string{})
+}
+
+// describe returns a short description of what the scheme expects.
+func (s *SecurityScheme) describe() string {
+	switch s.Type {
+	case "apiKey":
+		in := "header"
+		switch s.In {
+		case "query":
+			in = "query parameter"
+		case "cookie":
+			in = "cookie"
+		}
+		return fmt.Sprintf("API key in the %q %s", s.ParamName, in)
+	case "http":
+		if strings.EqualFold(s.Scheme, "basic") {
+			return "user name and password, as user:password"
+		}
+		return fmt.Sprintf("%s token in the Authorization header", strings.ToLower(s.Scheme))
+	case "oauth2", "openIdConnect":
+		d := "OAuth 2.0 access token in the Authorization header"
+		if u := s.tokenURL(); u != "" {
+			d += fmt.Sprintf(", or configure auth with token_url = %q", u)
+		}
+		return d
+	}
+	return fmt.Sprintf("unsupported %q credential", s.Type)
+}
+
+// tokenURL returns the token URL of the scheme's OAuth 2.0 flows, if any.
+func (s *SecurityScheme) tokenURL() string {
+	if s.Flows != nil {
+		if s.Flows.ClientCredentials != nil {
+			return s.Flows.ClientCredentials.TokenURL
+		}
+		if s.Flows.AuthorizationCode != nil {
+			return s.Flows.AuthorizationCode.TokenURL
+		}
+	}
+	return s.TokenURL
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// expiryDelta is how long before its expiry a token is considered expired,
+// so it does not expire while a request is in flight.
+const expiryDelta = 30 * time.Second
+
+// Token is an OAuth 2.0 access token.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Expiry is the zero time if the token endpoint did not say when the
+	// token expires.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+func (t *Token) valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(t.Expiry)
+}
+
+// DefaultCacheDir returns the directory tokens are cached in.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aepcli", "tokens"), nil
+}
+
+// fileCache stores tokens as JSON files in a directory that only the user
+// can read.
+type fileCache struct {
+	dir string
+}
+
+// cacheKey returns the file name for the token identified by the parts.
+func cacheKey(parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(h[:]) + ".json"
+}
+
+// load returns the cached token, or nil if there is none.
+func (c fileCache) load(key string) *Token {
+	var t Token
+	if !c.read(key, &t) {
+		return nil
+	}
+	return &t
+}
+
+func (c fileCache) store(key string, t *Token) error {
+	return c.write(key, t)
+}
+
+// read decodes the cached value into v, returning false if there is none.
+func (c fileCache) read(key string, v interface{}) bool {
+	if c.dir == "" {
+		return false
+	}
+	b, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		slog.Debug("Ignoring invalid cached token", "file", key, "error", err)
+		return false
+	}
+	return true
+}
+
+func (c fileCache) write(key string, v interface{}) error {
+	if c.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("unable to create token cache directory: %v", err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(c.dir, key)
+	if err := os.WriteFile(p, b, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of existing files.
+	return os.Chmod(p, 0600)
+}
+
+func (c fileCache) remove(key string) {
+	if c.dir != "" {
+		os.Remove(filepath.Join(c.dir, key))
+	}
+}
@@ -0,0 +1,236 @@
+// Package cassette records HTTP interactions to a file, and replays them
+// without a network, e.g. for integration tests and reproducing bugs.
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aep-dev/aepcli/internal/redact"
+	"gopkg.in/yaml.v3"
+)
+
+// Cassette is the file format of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Interaction is a request and the response it received.
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status  string      `yaml:"status"`
+	Code    int         `yaml:"code"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// Load reads a cassette from a file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unable to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to a file. It is only readable by the user, as
+// responses can contain secrets.
+func (c *Cassette) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Recorder is an http.RoundTripper that records every interaction to a
+// cassette file. Interactions are appended to the cassette if it exists, so
+// the commands of a script can be recorded to the same file.
+type Recorder struct {
+	path   string
+	next   http.RoundTripper
+	redact *redact.Redactor
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder returns a recorder sending requests with next, or
+// http.DefaultTransport if it is nil. The values of the headers and query
+// parameters matched by the redactor are not recorded. Bodies are recorded
+// as they are, so they can be matched when replaying.
+func NewRecorder(path string, next http.RoundTripper, redactor *redact.Redactor) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c := &Cassette{}
+	if _, err := os.Stat(path); err == nil {
+		if c, err = Load(path); err != nil {
+			return nil, err
+		}
+	}
+	return &Recorder{path: path, next: next, redact: redactor, cassette: c}, nil
+}
+
+// RoundTrip sends the request, and records it with its response.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     r.redact.URL(req.URL),
+			Headers: r.headers(req.Header),
+			Body:    body,
+		},
+		Response: Response{
+			Status:  resp.Status,
+			Code:    resp.StatusCode,
+			Headers: r.headers(resp.Header),
+			Body:    string(respBody),
+		},
+	})
+	// the cassette is saved after every interaction, so it is complete even
+	// if the command is interrupted.
+	if err := r.cassette.Save(r.path); err != nil {
+		return nil, fmt.Errorf("unable to write cassette: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) headers(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	return r.redact.Headers(h)
+}
+
+// Replayer is an http.RoundTripper that serves the responses of a cassette,
+// without a network. Requests match interactions with the same method, URL
+// and body. The AEP-155 request_id is ignored, as it is generated again for
+// each command, and so are the values of redacted query parameters, which
+// were not recorded. Matching interactions are served in the order they were
+// recorded, and the last one is served again once they are used up.
+type Replayer struct {
+	redact *redact.Redactor
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer returns a replayer for the cassette file. The redactor must
+// match the query parameters redacted when the cassette was recorded.
+func NewReplayer(path string, redactor *redact.Redactor) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{redact: redactor, cassette: c, used: make([]bool, len(c.Interactions))}, nil
+}
+
+// RoundTrip returns the recorded response to the request.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	target := r.matchURL(req.URL.String())
+	match := -1
+	for i, in := range r.cassette.Interactions {
+		if in.Request.Method != req.Method || r.matchURL(in.Request.URL) != target || in.Request.Body != body {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s\n\nTo fix this issue:\n  1. Record the request again with --record", req.Method, req.URL)
+	}
+	r.used[match] = true
+	recorded := r.cassette.Interactions[match].Response
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        recorded.Status,
+		StatusCode:    recorded.Code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matchURL returns the URL requests are matched by: without its request_id
+// query parameter, and with the values of the redacted ones replaced.
+func (r *Replayer) matchURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	q.Del("request_id")
+	u.RawQuery = q.Encode()
+	return r.redact.URL(u)
+}
+
+// readRequestBody returns the body of the request, leaving it to be sent.
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
@@ -0,0 +1,116 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aep-dev/aepcli/internal/redact"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("X-Server", "bookstore")
+		w.Header().Set("X-Session", "session-id")
+		switch {
+		case r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		default:
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"done": false}`))
+				return
+			}
+			w.Write([]byte(`{"done": true}`))
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	recorder, err := NewRecorder(path, nil, redact.New([]string{"X-Session"}, nil))
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+	send := func(client *http.Client, method, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+"/publishers/acme?key=secret-key", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do(%s) error = %v", method, err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	send(client, http.MethodPost, `{"description": "x"}`)
+	send(client, http.MethodGet, "")
+	send(client, http.MethodGet, "")
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret-token") || strings.Contains(string(data), "session=abc") || strings.Contains(string(data), "session-id") {
+		t.Errorf("cassette contains redacted headers:\n%s", data)
+	}
+	if strings.Contains(string(data), "secret-key") || !strings.Contains(string(data), "key=REDACTED") {
+		t.Errorf("cassette contains the redacted key parameter:\n%s", data)
+	}
+	if !strings.Contains(string(data), "bookstore") {
+		t.Errorf("cassette does not contain the other headers:\n%s", data)
+	}
+
+	server.Close()
+	replayer, err := NewReplayer(path, redact.New([]string{"X-Session"}, nil))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+	if code, body := send(client, http.MethodPost, `{"description": "x"}`); code != http.StatusCreated || body != `{"description": "x"}` {
+		t.Errorf("replayed create = %d %s", code, body)
+	}
+	for _, want := range []string{`{"done": false}`, `{"done": true}`, `{"done": true}`} {
+		if _, body := send(client, http.MethodGet, ""); body != want {
+			t.Errorf("replayed get = %s, want %s", body, want)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/publishers/acme?request_id=d1c5e5a0&key=other-key", strings.NewReader(`{"description": "x"}`))
+	if resp, err := client.Do(req); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Errorf("Do() with a new request_id and key = %v, %v, want the recorded create", resp, err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/publishers/acme?key=secret-key", strings.NewReader(`{"description": "y"}`))
+	if _, err := client.Do(req); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Do() with an unrecorded body error = %v", err)
+	}
+}
+
+func TestRecorder_Appends(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	for i := 0; i < 2; i++ {
+		recorder, err := NewRecorder(path, nil, nil)
+		if err != nil {
+			t.Fatalf("NewRecorder() error = %v", err)
+		}
+		if _, err := (&http.Client{Transport: recorder}).Get(server.URL); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(c.Interactions) != 2 {
+		t.Errorf("interactions = %d, want the second command appended", len(c.Interactions))
+	}
+}
@@ -21,6 +21,68 @@ type API struct {
 	Headers     []string
 	PathPrefix  string
 	CACertPath  string
+	// ClientCert is a PEM certificate, or a PKCS#12 bundle, presented to
+	// servers that require mutual TLS. ClientKey is its PEM private key, if
+	// it is in a separate file, and ClientCertPassword decrypts bundles.
+	ClientCert         string `toml:"client_cert,omitempty"`
+	ClientKey          string `toml:"client_key,omitempty"`
+	ClientCertPassword string `toml:"client_cert_password,omitempty"`
+	// Protected requires every change to be confirmed by typing the path
+	// of the resource, or the name of the API for changes to many
+	// resources.
+	Protected bool `toml:"protected,omitempty"`
+	// ReadOnly blocks every command that changes resources.
+	ReadOnly bool `toml:"read_only,omitempty"`
+	// AllowedMethods, if set, lists the only commands that can be run,
+	// e.g. ["get", "list"].
+	AllowedMethods []string `toml:"allowed_methods"`
+	// Auth configures how aepcli authenticates to the API.
+	Auth *Auth `toml:"auth,omitempty"`
+	// Credentials are the credentials for the security schemes of the
+	// OpenAPI definition, keyed by scheme name.
+	Credentials map[string]string `toml:"credentials,omitempty"`
+	// CredentialHelper is a command printing the credentials to send, e.g.
+	// ["my-token-tool", "--audience", "x"].
+	CredentialHelper []string `toml:"credential_helper,omitempty"`
+	// Retries is how many times requests that failed with a transient error
+	// are retried, and RetryMaxWait the longest wait before a retry, e.g.
+	// "30s". The command-line flags take precedence.
+	Retries      *int   `toml:"retries,omitempty"`
+	RetryMaxWait string `toml:"retry_max_wait,omitempty"`
+	// Timeout is how long a request may take, e.g. "2m". The --timeout flag
+	// takes precedence.
+	Timeout string `toml:"timeout,omitempty"`
+	// ProxyURL is the http, https or socks5 proxy to connect through, and
+	// NoProxy the comma-separated hosts that are connected to directly, in
+	// the format of the NO_PROXY environment variable.
+	ProxyURL string `toml:"proxy_url,omitempty"`
+	NoProxy  string `toml:"no_proxy,omitempty"`
+	// UnixSocket is the path of a Unix domain socket the server listens on.
+	UnixSocket string `toml:"unix_socket,omitempty"`
+	// RedactHeaders are patterns of headers and query parameters, e.g.
+	// "X-*-Signature", and RedactFields names of body fields, that are
+	// redacted in logs, in addition to the credentials aepcli knows of.
+	RedactHeaders []string `toml:"redact_headers,omitempty"`
+	RedactFields  []string `toml:"redact_fields,omitempty"`
+}
+
+// Auth configures OAuth 2.0 authentication. If AuthorizationURL is set, users
+// log in with the authorization code grant, otherwise the client credentials
+// grant is used.
+type Auth struct {
+	TokenURL         string `toml:"token_url"`
+	AuthorizationURL string `toml:"authorization_url,omitempty"`
+	// RedirectPort is the port of the loopback redirect URI used during
+	// login. A free port is chosen if it is 0.
+	RedirectPort int    `toml:"redirect_port,omitempty"`
+	ClientID     string `toml:"client_id"`
+	// ClientSecret is the client secret. To keep it out of the
+	// configuration file, ClientSecretEnv can name an environment
+	// variable to read it from instead.
+	ClientSecret    string   `toml:"client_secret,omitempty"`
+	ClientSecretEnv string   `toml:"client_secret_env,omitempty"`
+	Scopes          []string `toml:"scopes,omitempty"`
+	Audience        string   `toml:"audience,omitempty"`
 }
 
 func ReadConfigFromFile(file string) (*Config, error) {
@@ -34,6 +96,13 @@ func ReadConfigFromFile(file string) (*Config, error) {
 	if _, err := toml.DecodeFile(file, &c); err != nil {
 		return nil, fmt.Errorf("unable to decode config file at %v: %v", file, err)
 	}
+	// APIs added by hand may not repeat their name.
+	for name, api := range c.APIs {
+		if api.Name == "" {
+			api.Name = name
+			c.APIs[name] = api
+		}
+	}
 	return &c, nil
 }
 
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -76,3 +77,38 @@ func TestReadConfigFromFile_NoFile(t *testing.T) {
 	assert.NotNil(t, cfg)
 	assert.Empty(t, cfg.APIs)
 }
+
+func TestReadConfigFromFile_Policies(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "config.toml")
+	content := `[apis.prod]
+serverurl = "https://prod.example.com"
+read_only = true
+allowed_methods = ["get", "list"]
+protected = true
+`
+	assert.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	cfg, err := ReadConfigFromFile(testFile)
+	assert.NoError(t, err)
+	api := cfg.APIs["prod"]
+	assert.Equal(t, "prod", api.Name)
+	assert.True(t, api.ReadOnly)
+	assert.True(t, api.Protected)
+	assert.Equal(t, []string{"get", "list"}, api.AllowedMethods)
+}
+
+func TestWriteAPIWithName_Policies(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "config.toml")
+	api := API{Name: "prod", OpenAPIPath: "/openapi.json", Protected: true, ReadOnly: true, AllowedMethods: []string{"get"}}
+	assert.NoError(t, WriteAPIWithName(testFile, api, false))
+
+	data, err := os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "protected = true")
+	assert.Contains(t, string(data), "read_only = true")
+
+	cfg, err := ReadConfigFromFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, api, cfg.APIs["prod"])
+}
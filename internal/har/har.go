@@ -0,0 +1,276 @@
+// Package har writes HTTP traffic to HTTP Archive (HAR 1.2) files, which
+// browser developer tools can open.
+package har
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/redact"
+	"github.com/aep-dev/aepcli/internal/timing"
+)
+
+// HAR is the root of an HTTP Archive.
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log is the list of recorded entries.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator is the application that created the archive.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a request and its response.
+type Entry struct {
+	StartedDateTime string   `json:"startedDateTime"`
+	Time            float64  `json:"time"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Cache           struct{} `json:"cache"`
+	Timings         Timings  `json:"timings"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Response is a recorded response. Requests that failed without a response,
+// e.g. because the server could not be reached, have status 0 and the error.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+	// Error is the custom field browsers record failed requests with.
+	Error string `json:"_error,omitempty"`
+}
+
+// NameValue is a header, cookie or query parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is the body of a request.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Content is the body of a response.
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Timings are the phases of a request in milliseconds, -1 if they did not
+// happen. Connect includes SSL, as the specification requires.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// Recorder is an http.RoundTripper that writes every request and response to
+// a HAR file, including retries, pages of lists and polls of long-running
+// operations.
+type Recorder struct {
+	path   string
+	next   http.RoundTripper
+	redact *redact.Redactor
+
+	mu  sync.Mutex
+	har HAR
+}
+
+// NewRecorder returns a recorder sending requests with next, or
+// http.DefaultTransport if it is nil. The headers, query parameters and body
+// fields matched by the redactor are replaced with REDACTED. The version of
+// aepcli is recorded as the creator of the file.
+func NewRecorder(path string, next http.RoundTripper, redactor *redact.Redactor, version string) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{
+		path:   path,
+		next:   next,
+		redact: redactor,
+		har:    HAR{Log: Log{Version: "1.2", Creator: Creator{Name: "aepcli", Version: version}, Entries: []Entry{}}},
+	}
+}
+
+// RoundTrip sends the request, and adds it with its response, or the error
+// it failed with, to the HAR file.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	traced, trace := timing.Start(req)
+	resp, err := r.next.RoundTrip(traced)
+	var respBody []byte
+	if err == nil {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	}
+	phases := trace.Done()
+
+	entry := Entry{
+		StartedDateTime: phases.Start.Format(time.RFC3339Nano),
+		Time:            milliseconds(phases.Total),
+		Request: Request{
+			Method:      req.Method,
+			URL:         r.redact.URL(req.URL),
+			HTTPVersion: req.Proto,
+			Cookies:     []NameValue{},
+			Headers:     r.headers(req.Header),
+			QueryString: []NameValue{},
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+		Response: r.response(resp, respBody),
+		Timings: Timings{
+			Blocked: milliseconds(phases.Blocked),
+			DNS:     optional(phases.DNS),
+			Connect: optional(phases.Connect + phases.TLS),
+			SSL:     optional(phases.TLS),
+			Send:    milliseconds(phases.Send),
+			Wait:    milliseconds(phases.Wait),
+			Receive: milliseconds(phases.Receive),
+		},
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			if r.redact.Matches(name) {
+				v = redact.Redacted
+			}
+			entry.Request.QueryString = append(entry.Request.QueryString, NameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(entry.Request.QueryString, func(i, j int) bool {
+		return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+	})
+	if body != nil {
+		entry.Request.PostData = &PostData{MimeType: req.Header.Get("Content-Type"), Text: r.redact.Body(string(body))}
+	}
+	if err != nil {
+		entry.Response.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.har.Log.Entries = append(r.har.Log.Entries, entry)
+	// the file is written after every request, so it is complete even if
+	// the command is interrupted.
+	if err := r.save(); err != nil {
+		return nil, fmt.Errorf("unable to write HAR file: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// response returns the recorded response, which is empty, with status 0, if
+// the request failed before there was one.
+func (r *Recorder) response(resp *http.Response, body []byte) Response {
+	if resp == nil {
+		return Response{
+			Cookies:     []NameValue{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+	}
+	return Response{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []NameValue{},
+		Headers:     r.headers(resp.Header),
+		Content: Content{
+			Size:     len(body),
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     r.redact.Body(string(body)),
+		},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+// headers returns the headers sorted by name, with redacted values.
+func (r *Recorder) headers(h http.Header) []NameValue {
+	headers := []NameValue{}
+	for name, values := range r.redact.Headers(h) {
+		for _, v := range values {
+			headers = append(headers, NameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// optional returns the duration in milliseconds, or -1 if the phase did not
+// happen.
+func optional(d time.Duration) float64 {
+	if d == 0 {
+		return -1
+	}
+	return milliseconds(d)
+}
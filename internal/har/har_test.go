@@ -0,0 +1,100 @@
+package har
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aep-dev/aepcli/internal/redact"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"path": "publishers/acme"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.har")
+	client := &http.Client{Transport: NewRecorder(path, nil, redact.New(nil, []string{"password"}), "v1.2.3")}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/publishers?id=acme", strings.NewReader(`{"description": "x", "password": "hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	if _, err := client.Do(req); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp, err := client.Get(server.URL + "/publishers/acme")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") || strings.Contains(string(data), "hunter2") {
+		t.Errorf("HAR contains redacted values:\n%s", data)
+	}
+	var h HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		t.Fatalf("invalid HAR: %v", err)
+	}
+	if h.Log.Version != "1.2" || len(h.Log.Entries) != 2 {
+		t.Fatalf("log = %+v, want 2 entries", h.Log)
+	}
+	if h.Log.Creator != (Creator{Name: "aepcli", Version: "v1.2.3"}) {
+		t.Errorf("creator = %+v", h.Log.Creator)
+	}
+	create := h.Log.Entries[0]
+	if create.Request.Method != http.MethodPost || create.Request.PostData == nil || create.Request.PostData.Text != `{"description":"x","password":"REDACTED"}` {
+		t.Errorf("request = %+v", create.Request)
+	}
+	if len(create.Request.QueryString) != 1 || create.Request.QueryString[0] != (NameValue{"id", "acme"}) {
+		t.Errorf("queryString = %v", create.Request.QueryString)
+	}
+	if create.Response.Status != http.StatusCreated || create.Response.Content.Text != `{"path": "publishers/acme"}` || create.Response.Content.MimeType != "application/json" {
+		t.Errorf("response = %+v", create.Response)
+	}
+	if create.Time <= 0 || create.Timings.Connect < 0 || create.Timings.Wait < 0 {
+		t.Errorf("time = %v, timings = %+v", create.Time, create.Timings)
+	}
+	// the connection of the first request is reused.
+	if get := h.Log.Entries[1]; get.Request.PostData != nil || get.Timings.Connect != -1 {
+		t.Errorf("get = %+v", get)
+	}
+}
+
+func TestRecorder_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	// requests to a closed server fail without a response.
+	server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.har")
+	client := &http.Client{Transport: NewRecorder(path, nil, redact.New(nil, nil), "v1.2.3")}
+	if _, err := client.Get(server.URL + "/publishers"); err == nil {
+		t.Fatal("Get() did not fail")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		t.Fatalf("invalid HAR: %v", err)
+	}
+	if len(h.Log.Entries) != 1 {
+		t.Fatalf("entries = %+v, want the failed request", h.Log.Entries)
+	}
+	if resp := h.Log.Entries[0].Response; resp.Status != 0 || !strings.Contains(resp.Error, "connect") {
+		t.Errorf("response = %+v, want status 0 and the error", resp)
+	}
+}
@@ -0,0 +1,182 @@
+// Package redact hides credentials and other secrets in the requests and
+// responses aepcli logs, prints and records.
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces the values that are redacted.
+const Redacted = "REDACTED"
+
+// DefaultHeaders are the patterns of the headers holding credentials. *
+// matches any characters, and case is ignored.
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "*-Key", "*-Token", "*-Secret"}
+
+// DefaultParameters are the query parameters commonly holding credentials.
+var DefaultParameters = []string{"key", "api_key", "apikey", "access_token", "token"}
+
+// Redactor redacts headers and query parameters matching patterns, and the
+// values of sensitive fields in JSON bodies. A nil Redactor redacts the
+// defaults.
+type Redactor struct {
+	patterns []string
+	fields   map[string]bool
+}
+
+// New returns a redactor for the default headers and parameters, the
+// patterns of other headers and parameters, and the JSON fields.
+func New(patterns, fields []string) *Redactor {
+	r := &Redactor{fields: map[string]bool{}}
+	for _, p := range append(append(append([]string{}, DefaultHeaders...), DefaultParameters...), patterns...) {
+		r.patterns = append(r.patterns, strings.ToLower(p))
+	}
+	for _, f := range fields {
+		r.fields[f] = true
+	}
+	return r
+}
+
+var defaultRedactor = New(nil, nil)
+
+func (r *Redactor) get() *Redactor {
+	if r == nil {
+		return defaultRedactor
+	}
+	return r
+}
+
+// Matches returns whether the header or query parameter is redacted.
+func (r *Redactor) Matches(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range r.get().patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Headers returns a copy of the headers, with the values of the redacted
+// ones replaced.
+func (r *Redactor) Headers(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	out := h.Clone()
+	for name, values := range out {
+		if r.Matches(name) {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	return out
+}
+
+// URL returns the URL, with the values of the redacted query parameters
+// replaced.
+func (r *Redactor) URL(u *url.URL) string {
+	q := u.Query()
+	redacted := false
+	for name, values := range q {
+		if r.Matches(name) {
+			for i := range values {
+				values[i] = Redacted
+			}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// Body returns the JSON body, with the values of the sensitive fields
+// replaced at any depth. Other bodies are returned as they are.
+func (r *Redactor) Body(body string) string {
+	r = r.get()
+	if len(r.fields) == 0 || body == "" {
+		return body
+	}
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	if !r.redactFields(v) {
+		return body
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
+
+// redactFields replaces the sensitive fields in the value, and returns
+// whether there were any.
+func (r *Redactor) redactFields(v any) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if r.fields[k] {
+				v[k] = Redacted
+				redacted = true
+			} else if r.redactFields(child) {
+				redacted = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if r.redactFields(child) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// SensitiveFields returns the names of the properties of the schemas in an
+// OpenAPI definition that are writeOnly or have the password format.
+func SensitiveFields(raw []byte) ([]string, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var fields []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if props, ok := v["properties"].(map[string]any); ok {
+				for name, p := range props {
+					schema, _ := p.(map[string]any)
+					if (schema["writeOnly"] == true || schema["format"] == "password") && !seen[name] {
+						seen[name] = true
+						fields = append(fields, name)
+					}
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+	sort.Strings(fields)
+	return fields, nil
+}
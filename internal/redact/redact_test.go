@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestRedactor_Matches(t *testing.T) {
+	r := New([]string{"X-*-Signature"}, nil)
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Authorization", true},
+		{"cookie", true},
+		{"X-Api-Key", true},
+		{"X-Auth-Token", true},
+		{"X-Request-Signature", true},
+		{"api_key", true},
+		{"Content-Type", false},
+		{"X-Keystone", false},
+		{"page_token_size", false},
+	}
+	for _, tt := range tests {
+		if got := r.Matches(tt.name); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	var defaults *Redactor
+	if !defaults.Matches("Authorization") || defaults.Matches("X-Request-Signature") {
+		t.Error("nil Redactor does not redact the defaults")
+	}
+}
+
+func TestRedactor_HeadersAndURL(t *testing.T) {
+	var r *Redactor
+	h := http.Header{"Authorization": {"Bearer secret"}, "Accept": {"application/json"}}
+	got := r.Headers(h)
+	if got.Get("Authorization") != Redacted || got.Get("Accept") != "application/json" {
+		t.Errorf("Headers() = %v", got)
+	}
+	if h.Get("Authorization") != "Bearer secret" {
+		t.Error("Headers() changed the original headers")
+	}
+
+	u, _ := url.Parse("https://bookstore.example.com/publishers?key=secret&page_size=10")
+	if got := r.URL(u); got != "https://bookstore.example.com/publishers?key=REDACTED&page_size=10" {
+		t.Errorf("URL() = %s", got)
+	}
+	if u.Query().Get("key") != "secret" {
+		t.Error("URL() changed the original URL")
+	}
+}
+
+func TestRedactor_Body(t *testing.T) {
+	r := New(nil, []string{"password", "apiSecret"})
+	tests := []struct {
+		body, want string
+	}{
+		{`{"name": "acme", "password": "hunter2"}`, `{"name":"acme","password":"REDACTED"}`},
+		{`{"users": [{"apiSecret": {"value": "x"}}]}`, `{"users":[{"apiSecret":"REDACTED"}]}`},
+		{`{"name": "acme"}`, `{"name": "acme"}`},
+		{`not json password`, `not json password`},
+	}
+	for _, tt := range tests {
+		if got := r.Body(tt.body); got != tt.want {
+			t.Errorf("Body(%s) = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestSensitiveFields(t *testing.T) {
+	raw := []byte(`{
+		"components": {"schemas": {
+			"user": {"properties": {
+				"name": {"type": "string"},
+				"password": {"type": "string", "format": "password"},
+				"settings": {"type": "object", "properties": {"apiSecret": {"type": "string", "writeOnly": true}}}
+			}}
+		}},
+		"definitions": {"login": {"properties": {"password": {"type": "string", "format": "password"}}}}
+	}`)
+	got, err := SensitiveFields(raw)
+	if err != nil {
+		t.Fatalf("SensitiveFields() error = %v", err)
+	}
+	if want := []string{"apiSecret", "password"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SensitiveFields() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Package secret resolves references to secrets in configuration values, so
+// the secrets themselves are not stored in the configuration file.
+package secret
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// reference matches ${env:NAME}, ${file:PATH} and ${cmd:COMMAND}.
+var reference = regexp.MustCompile(`\$\{(\w+):([^}]*)\}`)
+
+// commandOutputs memoizes the output of commands, so they run once per
+// invocation of aepcli rather than once per request.
+var commandOutputs sync.Map
+
+// HasReference returns whether the value refers to a secret.
+func HasReference(value string) bool {
+	return reference.MatchString(value)
+}
+
+// Resolve replaces the references in the value with the secrets they refer
+// to:
+//
+//   - ${env:NAME} is the value of the environment variable NAME.
+//   - ${file:PATH} is the content of the file, without trailing newlines. A
+//     leading ~ is the home directory.
+//   - ${cmd:COMMAND} is the output of the command, without trailing
+//     newlines. The command is split on spaces, and not run in a shell.
+func Resolve(value string) (string, error) {
+	var err error
+	resolved := reference.ReplaceAllStringFunc(value, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := reference.FindStringSubmatch(ref)
+		var v string
+		v, err = resolve(m[1], m[2])
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func resolve(kind, arg string) (string, error) {
+	switch kind {
+	case "env":
+		v, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("secret: environment variable %s is not set", arg)
+		}
+		return v, nil
+	case "file":
+		if arg == "~" || strings.HasPrefix(arg, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("secret: unable to get home directory: %v", err)
+			}
+			arg = filepath.Join(home, arg[1:])
+		}
+		b, err := os.ReadFile(arg)
+		if err != nil {
+			return "", fmt.Errorf("secret: unable to read %s: %v", arg, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case "cmd":
+		if v, ok := commandOutputs.Load(arg); ok {
+			return v.(string), nil
+		}
+		args := strings.Fields(arg)
+		if len(args) == 0 {
+			return "", fmt.Errorf("secret: ${cmd:} needs a command")
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("secret: command %q failed: %v", arg, err)
+		}
+		v := strings.TrimRight(string(out), "\r\n")
+		commandOutputs.Store(arg, v)
+		return v, nil
+	}
+	return "", fmt.Errorf("secret: unknown reference ${%s:...}, expected env, file or cmd", kind)
+}
+
+// Mask returns the value to show to users: references are shown as they are,
+// since they contain no secrets, and other values are hidden.
+func Mask(value string) string {
+	if value == "" || HasReference(value) {
+		return value
+	}
+	return "****"
+}
@@ -0,0 +1,63 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("AEPCLI_TEST_TOKEN", "env-token")
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"${env:AEPCLI_TEST_TOKEN}", "env-token"},
+		{"Bearer ${env:AEPCLI_TEST_TOKEN}", "Bearer env-token"},
+		{"${file:" + file + "}", "file-token"},
+		{"${cmd:echo cmd-token}", "cmd-token"},
+		{"${env:AEPCLI_TEST_TOKEN}:${cmd:echo two}", "env-token:two"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.value)
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	for value, want := range map[string]string{
+		"${env:AEPCLI_TEST_UNSET}":           "environment variable AEPCLI_TEST_UNSET is not set",
+		"${file:/nonexistent/aepcli/secret}": "unable to read",
+		"${cmd:/nonexistent/aepcli/command}": "failed",
+		"${vault:secret/token}":              "unknown reference ${vault:...}",
+	} {
+		if _, err := Resolve(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Resolve(%q) error = %v, want %q", value, err, want)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	for value, want := range map[string]string{
+		"123":                      "****",
+		"${env:ROBLOX_API_KEY}":    "${env:ROBLOX_API_KEY}",
+		"Bearer ${file:~/.secret}": "Bearer ${file:~/.secret}",
+		"":                         "",
+	} {
+		if got := Mask(value); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
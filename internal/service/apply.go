@@ -0,0 +1,291 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+const (
+	actionCreate    = "create"
+	actionUpdate    = "update"
+	actionDelete    = "delete"
+	actionUnchanged = "unchanged"
+)
+
+// applyAction is a single change needed to bring a resource to the state
+// declared in its manifest.
+type applyAction struct {
+	Kind     string
+	Path     string
+	Resource *api.Resource
+	// Data is the resource data to send, without read-only fields.
+	Data map[string]interface{}
+	// Diff is a field-level summary of the changes for updates.
+	Diff string
+}
+
+func (s *ServiceCommand) apply(args []string) (*Result, error) {
+	var files []string
+	var prune bool
+	var plan bool
+	var yes bool
+	run := false
+
+	c := &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update resources from manifest files",
+		Long: "Create or update resources from YAML or JSON manifest files. Each manifest declares\n" +
+			"the resource type, its full path, and its data:\n\n" +
+			"  type: book\n" +
+			"  path: publishers/acme/books/peter-pan\n" +
+			"  data:\n" +
+			"    title: Peter Pan\n\n" +
+			"Parents are applied before their children.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			run = true
+		},
+	}
+	c.Flags().StringArrayVarP(&files, "filename", "f", []string{}, "Manifest file or directory of manifest files to apply")
+	c.Flags().BoolVar(&prune, "prune", false, "Delete resources that are not declared in the manifests, in the collections the manifests belong to")
+	c.Flags().BoolVar(&plan, "plan", false, "Show the changes that would be made, without making them. Exits with code 1 if there are changes.")
+	addYesFlag(c, &yes)
+	c.MarkFlagRequired("filename")
+
+	var stdout strings.Builder
+	c.SetOut(&stdout)
+	c.SetArgs(args)
+	if err := c.Execute(); err != nil {
+		return &Result{Output: stdout.String()}, err
+	}
+	if !run {
+		return &Result{Output: stdout.String()}, nil
+	}
+
+	manifests, err := readManifests(files)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.planApply(manifests, prune)
+	if err != nil {
+		return nil, err
+	}
+	if plan {
+		return formatPlan(actions), nil
+	}
+	return s.executeApply(actions, yes)
+}
+
+// planApply compares the manifests with the live resources, and returns the
+// actions needed to apply them: creates and updates ordered parents first,
+// followed by deletes ordered children first.
+func (s *ServiceCommand) planApply(manifests []*manifest, prune bool) ([]*applyAction, error) {
+	managed := map[string]bool{}
+	for _, m := range manifests {
+		if _, err := resolveManifest(&s.API, m); err != nil {
+			return nil, err
+		}
+		if managed[m.Path] {
+			return nil, fmt.Errorf("resource %s is declared more than once (in '%s')", m.Path, m.source)
+		}
+		managed[m.Path] = true
+	}
+	sortByHierarchy(manifests)
+
+	actions := []*applyAction{}
+	created := map[string]bool{}
+	for _, m := range manifests {
+		r, err := resolveManifest(&s.API, m)
+		if err != nil {
+			return nil, err
+		}
+		desired := stripReadOnlyFields(m.Data, r.Schema)
+		a := &applyAction{Path: m.Path, Resource: r, Data: desired}
+
+		var live map[string]interface{}
+		exists := false
+		if !created[parentPath(m.Path)] {
+			result, data, err := s.doJSON(http.MethodGet, m.Path, nil)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case result == nil || result.StatusCode == http.StatusNotFound:
+			case result.StatusCode/100 == 2:
+				exists = true
+				live = data
+			default:
+				return nil, responseError(fmt.Sprintf("get %s", m.Path), result)
+			}
+		}
+
+		if !exists {
+			a.Kind = actionCreate
+			created[m.Path] = true
+		} else {
+			// only compare the fields declared in the manifest, at any
+			// depth, as the update is sent as a merge patch.
+			current := projectFields(stripReadOnlyFields(live, r.Schema), desired)
+			a.Diff = fieldDiff(current, desired, false)
+			if a.Diff == "" {
+				a.Kind = actionUnchanged
+			} else if r.Methods.Update == nil {
+				return nil, fmt.Errorf("resource %s differs from its manifest, but %q does not support update", m.Path, r.Singular)
+			} else {
+				a.Kind = actionUpdate
+			}
+		}
+		actions = append(actions, a)
+	}
+
+	if prune {
+		deletes, err := s.planPrune(manifests, managed, created)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, deletes...)
+	}
+	return actions, nil
+}
+
+// projectFields returns the fields of live that are declared in desired.
+// Nested objects are projected onto the fields declared in them, as a merge
+// patch leaves the others unchanged.
+func projectFields(live, desired map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, d := range desired {
+		v, ok := live[k]
+		if !ok {
+			continue
+		}
+		nestedLive, liveIsObject := v.(map[string]interface{})
+		nestedDesired, desiredIsObject := d.(map[string]interface{})
+		if liveIsObject && desiredIsObject {
+			v = projectFields(nestedLive, nestedDesired)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// planPrune lists the collections the manifests belong to, and returns
+// delete actions for the resources in them that are not managed.
+func (s *ServiceCommand) planPrune(manifests []*manifest, managed, created map[string]bool) ([]*applyAction, error) {
+	collections := map[string]*api.Resource{}
+	for _, m := range manifests {
+		if created[parentPath(m.Path)] {
+			// the collection can not contain anything yet.
+			continue
+		}
+		r, err := resolveManifest(&s.API, m)
+		if err != nil {
+			return nil, err
+		}
+		collections[collectionPath(m.Path)] = r
+	}
+	names := make([]string, 0, len(collections))
+	for c := range collections {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+
+	deletes := []*applyAction{}
+	for _, c := range names {
+		r := collections[c]
+		if r.Methods.List == nil || r.Methods.Delete == nil {
+			return nil, fmt.Errorf("unable to prune %s: %q does not support both list and delete", c, r.Singular)
+		}
+		items, err := s.listAll(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			p, err := itemPath(c, item)
+			if err != nil {
+				return nil, err
+			}
+			if !managed[p] {
+				deletes = append(deletes, &applyAction{Kind: actionDelete, Path: p, Resource: r})
+			}
+		}
+	}
+	sort.SliceStable(deletes, func(i, j int) bool {
+		di, dj := resourceDepth(deletes[i].Path), resourceDepth(deletes[j].Path)
+		if di != dj {
+			return di > dj
+		}
+		return deletes[i].Path < deletes[j].Path
+	})
+	return deletes, nil
+}
+
+func formatPlan(actions []*applyAction) *Result {
+	var output strings.Builder
+	counts := map[string]int{}
+	for _, a := range actions {
+		counts[a.Kind]++
+		output.WriteString(fmt.Sprintf("%-9s %s\n", a.Kind, a.Path))
+		if a.Kind == actionUpdate {
+			for _, line := range strings.Split(strings.TrimSuffix(a.Diff, "\n"), "\n") {
+				output.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	output.WriteString(fmt.Sprintf("Plan: %d to create, %d to update, %d to delete, %d unchanged.",
+		counts[actionCreate], counts[actionUpdate], counts[actionDelete], counts[actionUnchanged]))
+	changes := counts[actionCreate] + counts[actionUpdate] + counts[actionDelete]
+	return &Result{Output: output.String(), HasDiff: changes > 0}
+}
+
+// executeApply makes the changes of a plan. Plans that delete resources, and
+// any plan on a protected API, have to be confirmed unless yes is set.
+func (s *ServiceCommand) executeApply(actions []*applyAction, yes bool) (*Result, error) {
+	plan := formatPlan(actions)
+	destructive := false
+	for _, a := range actions {
+		destructive = destructive || a.Kind == actionDelete
+	}
+	if plan.HasDiff {
+		if err := s.confirmChange(plan.Output+"\nApply these changes?", s.Name, destructive, yes); err != nil {
+			return nil, err
+		}
+	}
+
+	var output strings.Builder
+	counts := map[string]int{}
+	for _, a := range actions {
+		var result *Result
+		var err error
+		switch a.Kind {
+		case actionUnchanged:
+			counts[a.Kind]++
+			continue
+		case actionCreate:
+			p, perr := createPath(a.Resource, a.Path)
+			if perr != nil {
+				return &Result{Output: output.String()}, perr
+			}
+			result, _, err = s.doJSON(http.MethodPost, p, a.Data)
+		case actionUpdate:
+			result, _, err = s.doJSON(http.MethodPatch, a.Path, a.Data)
+		case actionDelete:
+			result, _, err = s.doJSON(http.MethodDelete, a.Path, nil)
+		}
+		if err != nil {
+			return &Result{Output: output.String()}, err
+		}
+		if result != nil && result.StatusCode/100 != 2 {
+			return &Result{Output: output.String(), StatusCode: result.StatusCode}, responseError(fmt.Sprintf("%s %s", a.Kind, a.Path), result)
+		}
+		counts[a.Kind]++
+		output.WriteString(fmt.Sprintf("%sd %s\n", strings.TrimSuffix(a.Kind, "e"), a.Path))
+	}
+	output.WriteString(fmt.Sprintf("Apply complete: %d created, %d updated, %d deleted, %d unchanged.",
+		counts[actionCreate], counts[actionUpdate], counts[actionDelete], counts[actionUnchanged]))
+	return &Result{Output: output.String()}, nil
+}
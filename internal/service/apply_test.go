@@ -0,0 +1,228 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+func writeManifests(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestReadManifests(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"publisher.yaml": "type: publisher\npath: publishers/acme\ndata:\n  description: ACME\n---\ntype: publisher\npath: /publishers/other/\n",
+		"books/all.json": `[{"type": "book", "path": "publishers/acme/books/peter-pan", "data": {"title": "Peter Pan"}}]`,
+		"README.md":      "not a manifest",
+	})
+	manifests, err := readManifests([]string{dir})
+	if err != nil {
+		t.Fatalf("readManifests() error = %v", err)
+	}
+	sortByHierarchy(manifests)
+	sorted := []string{}
+	for _, m := range manifests {
+		sorted = append(sorted, m.Path)
+	}
+	want := []string{"publishers/acme", "publishers/other", "publishers/acme/books/peter-pan"}
+	if !reflect.DeepEqual(sorted, want) {
+		t.Errorf("readManifests() paths = %v, want %v", sorted, want)
+	}
+
+	if _, err := readManifests([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("readManifests() expected error for missing path")
+	}
+}
+
+func TestApply(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	f.put("publishers/acme", map[string]interface{}{"description": "old"})
+	f.put("publishers/acme/books/unchanged", map[string]interface{}{"title": "Same", "create_time": "2024-01-01"})
+	f.put("publishers/acme/books/stale", map[string]interface{}{"title": "Stale"})
+
+	dir := writeManifests(t, map[string]string{
+		"acme.yaml": "type: publisher\npath: publishers/acme\ndata:\n  description: new\n",
+		"books.yaml": strings.Join([]string{
+			"type: book\npath: publishers/acme/books/unchanged\ndata:\n  title: Same\n  create_time: ignored\n",
+			"type: book\npath: publishers/acme/books/new\ndata:\n  title: New\n",
+		}, "---\n"),
+		"edition.json": `{"type": "book-edition", "path": "publishers/acme/books/new/editions/first", "data": {"displayname": "First"}}`,
+	})
+
+	result, err := svc.Execute([]string{"apply", "-f", dir, "--prune", "--plan"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	wantPlan := strings.Join([]string{
+		"update    publishers/acme",
+		`    ~ description: "old" -> "new"`,
+		"create    publishers/acme/books/new",
+		"unchanged publishers/acme/books/unchanged",
+		"create    publishers/acme/books/new/editions/first",
+		"delete    publishers/acme/books/stale",
+		"Plan: 2 to create, 1 to update, 1 to delete, 1 unchanged.",
+	}, "\n")
+	if result.Output != wantPlan {
+		t.Errorf("Execute() plan =\n%s\nwant\n%s", result.Output, wantPlan)
+	}
+	if !result.HasDiff {
+		t.Error("Execute() plan expected HasDiff to be set")
+	}
+	if m := f.mutations(); len(m) != 0 {
+		t.Errorf("plan made mutations: %v", m)
+	}
+
+	result, err = svc.Execute([]string{"apply", "-f", dir, "--prune", "--yes"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	wantMutations := []string{
+		"PATCH publishers/acme",
+		"POST publishers/acme/books?id=new",
+		"POST publishers/acme/books/new/editions?id=first",
+		"DELETE publishers/acme/books/stale",
+	}
+	if m := f.mutations(); !reflect.DeepEqual(m, wantMutations) {
+		t.Errorf("apply mutations = %v, want %v", m, wantMutations)
+	}
+	if !strings.HasSuffix(result.Output, "Apply complete: 2 created, 1 updated, 1 deleted, 1 unchanged.") {
+		t.Errorf("Execute() output = %q", result.Output)
+	}
+	if p, _ := f.get("publishers/acme"); p["description"] != "new" {
+		t.Errorf("publisher description = %v, want new", p["description"])
+	}
+
+	// applying again is a no-op.
+	result, err = svc.Execute([]string{"apply", "-f", dir, "--plan"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.HasDiff {
+		t.Errorf("Execute() second plan has changes:\n%s", result.Output)
+	}
+}
+
+func TestApply_NestedFields(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	book := svc.API.Resources["book"]
+	book.Schema.Properties["details"] = openapi.Schema{
+		Type: "object",
+		Properties: map[string]openapi.Schema{
+			"pages":      {Type: "integer"},
+			"format":     {Type: "string"},
+			"print_time": {Type: "string", ReadOnly: true},
+		},
+	}
+	f.put("publishers/acme", map[string]interface{}{})
+	f.put("publishers/acme/books/peter-pan", map[string]interface{}{
+		"title":   "Peter Pan",
+		"details": map[string]interface{}{"pages": 100, "format": "hardcover", "print_time": "2024-01-01"},
+	})
+	dir := writeManifests(t, map[string]string{
+		"book.yaml": "type: book\npath: publishers/acme/books/peter-pan\ndata:\n  title: Peter Pan\n  details:\n    pages: 200\n",
+	})
+
+	result, err := svc.Execute([]string{"apply", "-f", dir, "--plan"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	wantPlan := "update    publishers/acme/books/peter-pan\n    ~ details.pages: 100 -> 200\nPlan: 0 to create, 1 to update, 0 to delete, 0 unchanged."
+	if result.Output != wantPlan {
+		t.Errorf("Execute() plan =\n%s\nwant\n%s", result.Output, wantPlan)
+	}
+	if _, err := svc.Execute([]string{"apply", "-f", dir, "--yes"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	result, err = svc.Execute([]string{"apply", "-f", dir, "--plan"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "unchanged publishers/acme/books/peter-pan\nPlan: 0 to create, 0 to update, 0 to delete, 1 unchanged."; result.Output != want {
+		t.Errorf("Execute() second plan =\n%s\nwant\n%s", result.Output, want)
+	}
+}
+
+func TestApply_InvalidManifest(t *testing.T) {
+	svc, _ := newBookstoreService(t)
+	tests := []struct {
+		name     string
+		manifest string
+		expected string
+	}{
+		{
+			name:     "missing type",
+			manifest: "path: publishers/acme\n",
+			expected: "has no type",
+		},
+		{
+			name:     "unknown path",
+			manifest: "type: publisher\npath: authors/foo\n",
+			expected: "no resource found matching path \"authors/foo\"",
+		},
+		{
+			name:     "type mismatch",
+			manifest: "type: book\npath: publishers/acme\n",
+			expected: "declares type \"book\", but path publishers/acme is a \"publisher\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeManifests(t, map[string]string{"m.yaml": tt.manifest})
+			_, err := svc.Execute([]string{"apply", "-f", dir})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Execute() error = %v, want it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApply_Protected(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"acme.yaml": "type: publisher\npath: publishers/acme\ndata:\n  description: new\n",
+	})
+	tests := []struct {
+		name          string
+		apiName       string
+		answer        string
+		wantMutations int
+		errorContains string
+	}{
+		{"confirmed with the configured name", "bookstore-prod", "bookstore-prod", 1, ""},
+		{"the OpenAPI title does not confirm", "bookstore-prod", "bookstore", 0, "cancelled"},
+		{"no name is never confirmed", "", "", 0, "has no name to type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, f := newBookstoreService(t)
+			svc.Name = tt.apiName
+			svc.Protected = true
+			svc.prompt = func(question string) (string, error) { return tt.answer, nil }
+			_, err := svc.Execute([]string{"apply", "-f", dir})
+			if tt.errorContains == "" && err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if tt.errorContains != "" && (err == nil || !strings.Contains(err.Error(), tt.errorContains)) {
+				t.Errorf("Execute() error = %v, want %q", err, tt.errorContains)
+			}
+			if m := f.mutations(); len(m) != tt.wantMutations {
+				t.Errorf("mutations = %v, want %d", m, tt.wantMutations)
+			}
+		})
+	}
+}
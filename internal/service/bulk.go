@@ -0,0 +1,425 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aep-dev/aep-lib-go/pkg/constants"
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	bulkFormatCSV    = "csv"
+	bulkFormatNDJSON = "ndjson"
+)
+
+// bulkFlags are the flags of commands that can read their input from a file,
+// running one request per row.
+type bulkFlags struct {
+	fromFile        string
+	concurrency     int
+	continueOnError bool
+	failuresFile    string
+}
+
+func addBulkFlags(cmd *cobra.Command, f *bulkFlags) {
+	cmd.Flags().StringVar(&f.fromFile, "from-file", "", "Run one request per row of a CSV or NDJSON file. Columns map to fields, parent ids and the id")
+	cmd.Flags().IntVar(&f.concurrency, "concurrency", 1, "Number of requests to run in parallel with --from-file")
+	cmd.Flags().BoolVar(&f.continueOnError, "continue-on-error", false, "Keep going when a row fails with --from-file")
+	cmd.Flags().StringVar(&f.failuresFile, "failures-file", "", "File to write failed rows to with --from-file. Defaults to the input file name with a .failed suffix")
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		if f.fromFile != "" {
+			relaxRequiredFlags(cmd)
+		}
+	}
+}
+
+// bulkArgs accepts n positional arguments, or none with --from-file, in
+// which case the arguments are read from each row.
+func bulkArgs(n int, f *bulkFlags) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if f.fromFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(n)(cmd, args)
+	}
+}
+
+// relaxRequiredFlags removes the required annotation from all flags, as the
+// values are provided by the rows of the input file instead.
+func relaxRequiredFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		delete(f.Annotations, cobra.BashCompOneRequiredFlag)
+	})
+}
+
+// bulkCommand runs one request per row of an input file.
+type bulkCommand struct {
+	// Input is the file the rows were read from.
+	Input           string
+	Method          string
+	Requests        []*bulkRequest
+	Concurrency     int
+	ContinueOnError bool
+	// the input format and the columns of CSV input, used to write
+	// the failed rows in the same format.
+	Format       string
+	Columns      []string
+	FailuresFile string
+}
+
+// bulkRequest is the request built from a single row.
+type bulkRequest struct {
+	// Row is the 1-indexed row in the input file, excluding any header.
+	Row     int
+	Record  map[string]interface{}
+	Request *http.Request
+	// Err is set if no request could be built from the row.
+	Err error
+}
+
+// bulkSpec describes how to build a request from a row.
+type bulkSpec struct {
+	Method string
+	// Schema is used to convert CSV values and to find the body fields.
+	// It is nil for requests without a body.
+	Schema *openapi.Schema
+	// Required lists the fields each row must set.
+	Required []string
+	// NeedsID is true if each row must have an "id" column.
+	NeedsID bool
+	// Parents are the names of the parent id columns, in pattern order.
+	Parents []string
+	// ParentDefaults are the parent ids set with flags, used for rows
+	// without the corresponding column.
+	ParentDefaults []string
+	// Path returns the request path for a row.
+	Path func(parents []string, id string) string
+}
+
+// newBulkCommand reads the rows of the input file, and builds a request for
+// each of them.
+func newBulkCommand(f *bulkFlags, spec bulkSpec) (*bulkCommand, error) {
+	if f.concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1, got %d", f.concurrency)
+	}
+	records, format, columns, err := readRows(f.fromFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no rows found in '%s'", f.fromFile)
+	}
+	failuresFile := f.failuresFile
+	if failuresFile == "" {
+		ext := filepath.Ext(f.fromFile)
+		failuresFile = strings.TrimSuffix(f.fromFile, ext) + ".failed" + ext
+	}
+	b := &bulkCommand{
+		Input:           f.fromFile,
+		Method:          spec.Method,
+		Concurrency:     f.concurrency,
+		ContinueOnError: f.continueOnError,
+		Format:          format,
+		Columns:         columns,
+		FailuresFile:    failuresFile,
+	}
+	for i, record := range records {
+		req, err := spec.request(record, format == bulkFormatCSV)
+		b.Requests = append(b.Requests, &bulkRequest{Row: i + 1, Record: record, Request: req, Err: err})
+	}
+	return b, nil
+}
+
+func (spec bulkSpec) request(record map[string]interface{}, fromCSV bool) (*http.Request, error) {
+	fields := map[string]interface{}{}
+	for k, v := range record {
+		fields[k] = v
+	}
+	takeString := func(name string) (string, bool) {
+		v, ok := fields[name]
+		delete(fields, name)
+		if !ok || v == nil || v == "" {
+			return "", false
+		}
+		return fmt.Sprintf("%v", v), true
+	}
+
+	parents := make([]string, len(spec.Parents))
+	for i, name := range spec.Parents {
+		v, ok := takeString(name)
+		if !ok {
+			v = spec.ParentDefaults[i]
+		}
+		if v == "" {
+			return nil, fmt.Errorf("missing parent %q: set the column or the --%s flag", name, name)
+		}
+		parents[i] = v
+	}
+	id := ""
+	if spec.NeedsID {
+		v, ok := takeString(constants.FIELD_ID_NAME)
+		if !ok {
+			return nil, fmt.Errorf("missing %q column", constants.FIELD_ID_NAME)
+		}
+		id = v
+	}
+	p := spec.Path(parents, id)
+
+	if spec.Schema == nil {
+		return http.NewRequest(spec.Method, p, nil)
+	}
+	body := map[string]interface{}{}
+	for k, v := range fields {
+		if fromCSV {
+			s, _ := v.(string)
+			if s == "" {
+				// empty cells leave the field unset.
+				continue
+			}
+			converted, err := convertValue(s, spec.Schema.Properties[k])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %q: %v", k, err)
+			}
+			v = converted
+		}
+		body[k] = v
+	}
+	for _, r := range spec.Required {
+		if _, ok := body[r]; !ok {
+			return nil, fmt.Errorf("missing required field %q", r)
+		}
+	}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling JSON: %v", err)
+	}
+	return http.NewRequest(spec.Method, p, strings.NewReader(string(jsonBody)))
+}
+
+// convertValue converts a CSV cell to the type of the field, in the same way
+// the field flags are parsed.
+func convertValue(s string, prop openapi.Schema) (interface{}, error) {
+	switch prop.Type {
+	case "", "string":
+		return s, nil
+	case "integer":
+		return strconv.Atoi(s)
+	case "number":
+		return strconv.ParseFloat(s, 64)
+	case "boolean":
+		return strconv.ParseBool(s)
+	case "array":
+		var value []interface{}
+		if err := (&ArrayFlag{&value, ""}).Set(s); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		var value interface{}
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// readRows reads the records of a CSV file with a header row, or of an
+// NDJSON file. The format is detected from the file extension.
+func readRows(path string) ([]map[string]interface{}, string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil, fmt.Errorf("unable to read file '%s': no such file or directory", path)
+		}
+		return nil, "", nil, fmt.Errorf("unable to read file '%s': %v", path, err)
+	}
+	records := []map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		r := csv.NewReader(bytes.NewReader(data))
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("invalid CSV in '%s': %v", path, err)
+		}
+		if len(rows) == 0 {
+			return records, bulkFormatCSV, nil, nil
+		}
+		columns := rows[0]
+		for _, row := range rows[1:] {
+			record := map[string]interface{}{}
+			for i, c := range columns {
+				record[c] = row[i]
+			}
+			records = append(records, record)
+		}
+		return records, bulkFormatCSV, columns, nil
+	case ".ndjson", ".jsonl":
+		s := bufio.NewScanner(bytes.NewReader(data))
+		s.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+		line := 0
+		for s.Scan() {
+			line++
+			if strings.TrimSpace(s.Text()) == "" {
+				continue
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(s.Bytes(), &record); err != nil {
+				return nil, "", nil, fmt.Errorf("invalid JSON in '%s' at line %d: %v", path, line, err)
+			}
+			records = append(records, record)
+		}
+		if err := s.Err(); err != nil {
+			return nil, "", nil, fmt.Errorf("unable to read file '%s': %v", path, err)
+		}
+		return records, bulkFormatNDJSON, nil, nil
+	default:
+		return nil, "", nil, fmt.Errorf("unsupported file '%s': expected a .csv, .ndjson or .jsonl file", path)
+	}
+}
+
+// bulkOutcome is the outcome of a single row.
+type bulkOutcome struct {
+	req     *bulkRequest
+	err     error
+	skipped bool
+}
+
+// executeBulk runs the requests of a bulk command with bounded concurrency,
+// and writes the rows that failed to the failures file.
+func (s *ServiceCommand) executeBulk(b *bulkCommand) (*Result, error) {
+	outcomes := make([]*bulkOutcome, len(b.Requests))
+	var mu sync.Mutex
+	failed := false
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < b.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				mu.Lock()
+				stop := failed && !b.ContinueOnError
+				mu.Unlock()
+				if stop {
+					outcomes[i] = &bulkOutcome{req: b.Requests[i], skipped: true}
+					continue
+				}
+				outcomes[i] = s.runBulkRequest(b.Requests[i])
+				if outcomes[i].err != nil {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range b.Requests {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	var output strings.Builder
+	succeeded, skipped := 0, 0
+	failures := []*bulkRequest{}
+	for _, o := range outcomes {
+		switch {
+		case o.skipped:
+			skipped++
+			failures = append(failures, o.req)
+		case o.err != nil:
+			failures = append(failures, o.req)
+			output.WriteString(fmt.Sprintf("row %d: %v\n", o.req.Row, o.err))
+		default:
+			succeeded++
+		}
+	}
+	output.WriteString(fmt.Sprintf("%d succeeded, %d failed, %d skipped.", succeeded, len(failures)-skipped, skipped))
+	if len(failures) == 0 {
+		return &Result{Output: output.String()}, nil
+	}
+	if err := writeFailures(b, failures); err != nil {
+		return &Result{Output: output.String()}, err
+	}
+	output.WriteString(fmt.Sprintf("\nFailed and skipped rows were written to %s", b.FailuresFile))
+	return &Result{Output: output.String()}, fmt.Errorf("%d of %d rows did not succeed", len(failures), len(b.Requests))
+}
+
+func (s *ServiceCommand) runBulkRequest(b *bulkRequest) *bulkOutcome {
+	if b.Err != nil {
+		return &bulkOutcome{req: b, err: b.Err}
+	}
+	req := b.Request
+	u, err := s.absoluteURL(req.URL.String())
+	if err != nil {
+		return &bulkOutcome{req: b, err: err}
+	}
+	req.URL = u
+	result, err := s.doRequest(req)
+	if err != nil {
+		return &bulkOutcome{req: b, err: err}
+	}
+	if result != nil && result.StatusCode/100 != 2 {
+		return &bulkOutcome{req: b, err: fmt.Errorf("status %d: %s", result.StatusCode, compactJSON(result.Output))}
+	}
+	return &bulkOutcome{req: b}
+}
+
+// writeFailures writes the failed rows to the failures file, in the format
+// of the input, so they can be fixed and re-run.
+func writeFailures(b *bulkCommand, failures []*bulkRequest) error {
+	f, err := os.Create(b.FailuresFile)
+	if err != nil {
+		return fmt.Errorf("unable to write failures file '%s': %v", b.FailuresFile, err)
+	}
+	defer f.Close()
+	if b.Format == bulkFormatCSV {
+		w := csv.NewWriter(f)
+		if err := w.Write(b.Columns); err != nil {
+			return err
+		}
+		for _, r := range failures {
+			row := make([]string, len(b.Columns))
+			for i, c := range b.Columns {
+				row[i] = fmt.Sprintf("%v", r.Record[c])
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+	for _, r := range failures {
+		line, err := json.Marshal(r.Record)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compactJSON returns the JSON on a single line, or the input unchanged if
+// it is not valid JSON.
+func compactJSON(s string) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(s)); err != nil {
+		return s
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	return p
+}
+
+func TestBulk_CreateFromCSV(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	f.put("publishers/acme", nil)
+	f.put("publishers/other", nil)
+	f.put("publishers/acme/books/exists", nil)
+
+	input := writeTestFile(t, "books.csv", strings.Join([]string{
+		"id,publisher,title,author",
+		"a,acme,A,Alice",
+		"exists,acme,Exists,",
+		"b,,B,",
+		",other,No ID,",
+		"c,other,C,Carol",
+	}, "\n")+"\n")
+
+	result, err := svc.Execute([]string{"book", "--publisher=acme", "create", "--from-file", input, "--continue-on-error", "--concurrency=2"})
+	if err == nil || err.Error() != "2 of 5 rows did not succeed" {
+		t.Errorf("Execute() error = %v", err)
+	}
+	for _, want := range []string{
+		"row 2: status 409: {\"message\":\"already exists\"}\n",
+		"row 4: missing \"id\" column\n",
+		"3 succeeded, 2 failed, 0 skipped.\n",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("Execute() output = %q, want it to contain %q", result.Output, want)
+		}
+	}
+
+	mutations := f.mutations()
+	sort.Strings(mutations)
+	wantMutations := []string{
+		"POST publishers/acme/books?id=a",
+		"POST publishers/acme/books?id=b",
+		"POST publishers/acme/books?id=exists",
+		"POST publishers/other/books?id=c",
+	}
+	if !reflect.DeepEqual(mutations, wantMutations) {
+		t.Errorf("mutations = %v, want %v", mutations, wantMutations)
+	}
+	if b, _ := f.get("publishers/acme/books/a"); b["title"] != "A" || b["author"] != "Alice" {
+		t.Errorf("created book = %v", b)
+	}
+	if b, _ := f.get("publishers/acme/books/b"); b["author"] != nil {
+		t.Errorf("empty cell was sent: %v", b)
+	}
+
+	failures, err := os.ReadFile(filepath.Join(filepath.Dir(input), "books.failed.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read failures file: %v", err)
+	}
+	wantFailures := "id,publisher,title,author\nexists,acme,Exists,\n,other,No ID,\n"
+	if string(failures) != wantFailures {
+		t.Errorf("failures file = %q, want %q", string(failures), wantFailures)
+	}
+}
+
+func TestBulk_StopOnError(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	f.put("publishers/acme", nil)
+	f.put("publishers/acme/books/a", nil)
+
+	input := writeTestFile(t, "books.ndjson", strings.Join([]string{
+		`{"id": "missing"}`,
+		`{"id": "a", "title": "A"}`,
+	}, "\n"))
+	failures := filepath.Join(t.TempDir(), "retry.ndjson")
+
+	result, err := svc.Execute([]string{"book", "--publisher=acme", "update", "--from-file", input, "--failures-file", failures})
+	if err == nil {
+		t.Error("Execute() expected an error")
+	}
+	if !strings.Contains(result.Output, "0 succeeded, 1 failed, 1 skipped.") {
+		t.Errorf("Execute() output = %q", result.Output)
+	}
+	if m := f.mutations(); len(m) != 1 {
+		t.Errorf("mutations = %v, want only the first row", m)
+	}
+	b, err := os.ReadFile(failures)
+	if err != nil {
+		t.Fatalf("Failed to read failures file: %v", err)
+	}
+	want := "{\"id\":\"missing\"}\n{\"id\":\"a\",\"title\":\"A\"}\n"
+	if string(b) != want {
+		t.Errorf("failures file = %q, want %q", string(b), want)
+	}
+}
+
+func TestBulk_Delete(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	f.put("publishers/acme", nil)
+	f.put("publishers/acme/books/a", nil)
+	f.put("publishers/acme/books/b", nil)
+
+	input := writeTestFile(t, "books.ndjson", "{\"id\": \"a\", \"publisher\": \"acme\"}\n\n{\"id\": \"b\", \"publisher\": \"acme\"}\n")
+	result, err := svc.Execute([]string{"book", "delete", "--from-file", input, "--yes"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "2 succeeded, 0 failed, 0 skipped." {
+		t.Errorf("Execute() output = %q", result.Output)
+	}
+	if _, ok := f.get("publishers/acme/books/a"); ok {
+		t.Error("book a was not deleted")
+	}
+}
+
+func TestBulk_InvalidInput(t *testing.T) {
+	svc, _ := newBookstoreService(t)
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "positional id with from-file",
+			args:     []string{"publisher", "delete", "foo", "--from-file", writeTestFile(t, "p.csv", "id\na\n")},
+			expected: "unknown command \"foo\"",
+		},
+		{
+			name:     "unsupported extension",
+			args:     []string{"publisher", "delete", "--from-file", writeTestFile(t, "p.txt", "a")},
+			expected: "expected a .csv, .ndjson or .jsonl file",
+		},
+		{
+			name:     "no rows",
+			args:     []string{"publisher", "delete", "--from-file", writeTestFile(t, "p.csv", "id\n")},
+			expected: "no rows found",
+		},
+		{
+			name:     "invalid concurrency",
+			args:     []string{"publisher", "delete", "--concurrency=0", "--from-file", writeTestFile(t, "p.csv", "id\na\n")},
+			expected: "--concurrency must be at least 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Execute(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Execute() error = %v, want it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	a := getTestAPI()
+	props := a.Resources["project"].Schema.Properties
+	tests := []struct {
+		field    string
+		value    string
+		expected interface{}
+	}{
+		{"description", "text", "text"},
+		{"priority", "5", 5},
+		{"active", "true", true},
+		{"tags", "a,b", []interface{}{"a", "b"}},
+		{"metadata", `{"k": "v"}`, map[string]interface{}{"k": "v"}},
+	}
+	for _, tt := range tests {
+		got, err := convertValue(tt.value, props[tt.field])
+		if err != nil {
+			t.Errorf("convertValue(%q) error = %v", tt.value, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("convertValue(%q) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+	if _, err := convertValue("five", props["priority"]); err == nil {
+		t.Error("convertValue() expected error for invalid integer")
+	}
+}
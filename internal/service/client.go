@@ -0,0 +1,71 @@
+package service
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ClientOptions configure the HTTP client requests are sent with.
+type ClientOptions struct {
+	// Insecure disables the verification of server certificates.
+	Insecure bool
+	// CACertPath is a PEM file of the certificate authorities server
+	// certificates are verified with, instead of the system ones.
+	CACertPath string
+	// ClientCert is the certificate presented to servers requiring mutual
+	// TLS.
+	ClientCert ClientCertificate
+	// Network configures proxies and Unix sockets.
+	Network Network
+	// Timeout is how long a request may take. It is 0 for no timeout.
+	Timeout time.Duration
+}
+
+// NewHTTPClient returns a client for the options. It is used for every
+// request of an API, including those fetching its OpenAPI definition and
+// OAuth tokens.
+func NewHTTPClient(o ClientOptions) (*http.Client, error) {
+	client := &http.Client{Timeout: o.Timeout}
+
+	var tlsConfig *tls.Config
+	if o.Insecure {
+		slog.Debug("Loading TLS configuration")
+		slog.Debug("TLS certificate verification disabled (insecure mode)")
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	} else if o.CACertPath != "" {
+		slog.Debug("Loading TLS configuration")
+		// Load custom CA certificate
+		caCertPool, err := loadCACertificate(o.CACertPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{RootCAs: caCertPool}
+	} else {
+		// Use default system CA certificates
+		slog.Debug("Using system CA certificates")
+	}
+	if o.ClientCert.CertPath != "" {
+		cert, err := loadClientCertificate(o.ClientCert)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if o.ClientCert.KeyPath != "" {
+		return nil, fmt.Errorf("a client key was given without a client certificate\n\nTo fix this issue:\n  1. Pass the certificate with --client-cert\n  2. Or set client_cert in the API configuration")
+	}
+	if tlsConfig != nil || o.Network.customized() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		if err := o.Network.configure(transport); err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+	}
+	return client, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// writeClientCertificate writes a self-signed client certificate and its key
+// as PEM files, and as a PKCS#12 bundle encrypted with "secret".
+func writeClientCertificate(t *testing.T) (certPath, keyPath, bundlePath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "aepcli"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := pkcs12.Modern.Encode(key, cert, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath = filepath.Join(dir, "client.pem")
+	keyPath = filepath.Join(dir, "client-key.pem")
+	bundlePath = filepath.Join(dir, "client.p12")
+	for path, data := range map[string][]byte{
+		certPath:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPath:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		bundlePath: bundle,
+	} {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certPath, keyPath, bundlePath
+}
+
+func TestNewHTTPClient_ClientCertificate(t *testing.T) {
+	certPath, keyPath, bundlePath := writeClientCertificate(t)
+	combinedPath := filepath.Join(t.TempDir(), "combined.pem")
+	certData, _ := os.ReadFile(certPath)
+	keyData, _ := os.ReadFile(keyPath)
+	if err := os.WriteFile(combinedPath, append(certData, keyData...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "aepcli" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		cert ClientCertificate
+	}{
+		{"PEM certificate and key", ClientCertificate{CertPath: certPath, KeyPath: keyPath}},
+		{"PEM file with both", ClientCertificate{CertPath: combinedPath}},
+		{"PKCS#12 bundle", ClientCertificate{CertPath: bundlePath, Password: "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newClientService(t, server.URL, ClientOptions{Insecure: true, ClientCert: tt.cert})
+			result, err := svc.Execute([]string{"publisher", "get", "acme"})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.StatusCode != http.StatusOK {
+				t.Errorf("StatusCode = %d, want the client certificate to be accepted", result.StatusCode)
+			}
+		})
+	}
+}
+
+func TestLoadClientCertificate_Errors(t *testing.T) {
+	certPath, keyPath, bundlePath := writeClientCertificate(t)
+	tests := []struct {
+		name          string
+		cert          ClientCertificate
+		errorContains string
+	}{
+		{"missing certificate", ClientCertificate{CertPath: "/nonexistent/client.pem"}, "Failed to read client certificate from /nonexistent/client.pem: file does not exist"},
+		{"missing key", ClientCertificate{CertPath: certPath, KeyPath: "/nonexistent/key.pem"}, "Failed to read client key from /nonexistent/key.pem: file does not exist"},
+		{"certificate without key", ClientCertificate{CertPath: certPath}, "no private key found"},
+		{"key is not a key", ClientCertificate{CertPath: certPath, KeyPath: certPath}, "Failed to load client certificate"},
+		{"wrong bundle password", ClientCertificate{CertPath: bundlePath, Password: "wrong"}, "Verify the bundle password is correct"},
+		{"bundle with key", ClientCertificate{CertPath: bundlePath, KeyPath: keyPath}, "a PKCS#12 bundle contains its private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadClientCertificate(tt.cert)
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("loadClientCertificate() error = %v, want %q", err, tt.errorContains)
+			}
+		})
+	}
+
+	if _, err := NewHTTPClient(ClientOptions{ClientCert: ClientCertificate{KeyPath: keyPath}}); err == nil || !strings.Contains(err.Error(), "without a client certificate") {
+		t.Errorf("NewHTTPClient() with only a key error = %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/constants"
+	"golang.org/x/term"
+)
+
+// errNotInteractive is returned when a confirmation is required, but there is
+// no terminal to ask it on.
+var errNotInteractive = errors.New("confirmation required, but stdin is not a terminal: use --yes to proceed")
+
+// confirm asks the user to confirm an action, returning false if they
+// declined.
+func (s *ServiceCommand) confirm(question string) (bool, error) {
+	answer, err := s.ask(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// ask prints the question and returns the answer typed by the user.
+func (s *ServiceCommand) ask(question string) (string, error) {
+	if s.prompt != nil {
+		return s.prompt(question)
+	}
+	// the answer is read in the background, so cancelling the command
+	// does not wait for it.
+	type answer struct {
+		text string
+		err  error
+	}
+	answered := make(chan answer, 1)
+	go func() {
+		text, err := terminalPrompt(question)
+		answered <- answer{text, err}
+	}()
+	select {
+	case a := <-answered:
+		return a.text, a.err
+	case <-s.context().Done():
+		return "", s.context().Err()
+	}
+}
+
+// terminalPrompt asks the question on stderr and reads the answer from stdin,
+// which must be a terminal.
+func terminalPrompt(question string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errNotInteractive
+	}
+	fmt.Fprint(os.Stderr, question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("unable to read answer: %v", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// errCancelled is returned when the user declines to confirm a change, so
+// the command fails instead of reporting success.
+var errCancelled = errors.New("cancelled: the change was not confirmed")
+
+// confirmChange asks the user to confirm a change, unless yes is set or this
+// is a dry run, and returns errCancelled if they decline. Destructive
+// changes, such as deletes, always need to be confirmed. On protected APIs,
+// every change needs to be confirmed by typing its name: the path of the
+// resource for a change to a single resource, and the name the API is
+// configured with for a change to many resources.
+func (s *ServiceCommand) confirmChange(question, name string, destructive, yes bool) error {
+	if yes || s.DryRun || !(destructive || s.Protected) {
+		return nil
+	}
+	if !s.Protected {
+		ok, err := s.confirm(question)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errCancelled
+		}
+		return nil
+	}
+	// an empty name would be confirmed by pressing enter.
+	if name == "" {
+		return fmt.Errorf("unable to confirm the change: the protected API has no name to type\n\nTo fix this issue:\n  1. Use the API by the name it is configured with")
+	}
+	answer, err := s.ask(fmt.Sprintf("%s\nThis API is protected. Type %q to confirm: ", question, name))
+	if err != nil {
+		return err
+	}
+	if answer != name {
+		return errCancelled
+	}
+	return nil
+}
+
+// requestName returns the path of the resource a request applies to: the
+// custom method is stripped, and the id of a create request is appended.
+func requestName(req *http.Request) string {
+	p := strings.Trim(req.URL.Path, "/")
+	if i := strings.LastIndex(p, ":"); i > strings.LastIndex(p, "/") {
+		p = p[:i]
+	}
+	if id := req.URL.Query().Get(constants.FIELD_ID_NAME); id != "" && req.Method == http.MethodPost {
+		p += "/" + id
+	}
+	return p
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfirmChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		protected bool
+		args      []string
+		answer    string
+		// asked is true if the user should be prompted.
+		asked bool
+		// cancelled is true if the change should not be confirmed.
+		cancelled bool
+		mutations []string
+	}{
+		{
+			name:      "delete confirmed",
+			args:      []string{"publisher", "delete", "acme"},
+			answer:    "y",
+			asked:     true,
+			mutations: []string{"DELETE publishers/acme"},
+		},
+		{
+			name:      "delete declined",
+			args:      []string{"publisher", "delete", "acme"},
+			answer:    "n",
+			asked:     true,
+			cancelled: true,
+			mutations: []string{},
+		},
+		{
+			name:      "delete with --yes",
+			args:      []string{"publisher", "delete", "acme", "--yes"},
+			mutations: []string{"DELETE publishers/acme"},
+		},
+		{
+			name:      "update is not confirmed",
+			args:      []string{"publisher", "update", "acme", "--description=new"},
+			mutations: []string{"PATCH publishers/acme"},
+		},
+		{
+			name:      "protected update requires the name",
+			protected: true,
+			args:      []string{"publisher", "update", "acme", "--description=new"},
+			answer:    "y",
+			asked:     true,
+			cancelled: true,
+			mutations: []string{},
+		},
+		{
+			name:      "protected create with the name",
+			protected: true,
+			args:      []string{"publisher", "create", "other"},
+			answer:    "publishers/other",
+			asked:     true,
+			mutations: []string{"POST publishers?id=other"},
+		},
+		{
+			name:      "protected get is not confirmed",
+			protected: true,
+			args:      []string{"publisher", "get", "acme"},
+			mutations: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, f := newBookstoreService(t)
+			f.put("publishers/acme", nil)
+			svc.Protected = tt.protected
+			asked := false
+			svc.prompt = func(question string) (string, error) {
+				asked = true
+				return tt.answer, nil
+			}
+			_, err := svc.Execute(tt.args)
+			if tt.cancelled && err != errCancelled {
+				t.Fatalf("Execute() error = %v, want %v", err, errCancelled)
+			}
+			if !tt.cancelled && err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if asked != tt.asked {
+				t.Errorf("asked = %v, want %v", asked, tt.asked)
+			}
+			if m := f.mutations(); !reflect.DeepEqual(m, tt.mutations) {
+				t.Errorf("mutations = %v, want %v", m, tt.mutations)
+			}
+		})
+	}
+}
+
+func TestConfirmChange_NotInteractive(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	f.put("publishers/acme", nil)
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme"}); err != errNotInteractive {
+		t.Errorf("Execute() error = %v, want %v", err, errNotInteractive)
+	}
+	if _, ok := f.get("publishers/acme"); !ok {
+		t.Error("publishers/acme was deleted without confirmation")
+	}
+}
+
+func TestConfirmChange_Bulk(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	svc.Name = "bookstore-prod"
+	svc.Protected = true
+	file := filepath.Join(t.TempDir(), "publishers.jsonl")
+	if err := os.WriteFile(file, []byte(`{"id": "acme"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var question string
+	svc.prompt = func(q string) (string, error) {
+		question = q
+		return "bookstore-prod", nil
+	}
+	if _, err := svc.Execute([]string{"publisher", "create", "--from-file", file}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// changes to many resources are confirmed with the name of the API.
+	if !strings.Contains(question, `Type "bookstore-prod" to confirm`) {
+		t.Errorf("question = %q, want the API name to be typed", question)
+	}
+	if m := f.mutations(); len(m) != 1 {
+		t.Errorf("mutations = %v, want the create", m)
+	}
+}
+
+func TestRequestName(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		want   string
+	}{
+		{http.MethodDelete, "publishers/acme", "publishers/acme"},
+		{http.MethodPost, "publishers/acme/books?id=peter-pan", "publishers/acme/books/peter-pan"},
+		{http.MethodPost, "publishers/acme/books/peter-pan:archive", "publishers/acme/books/peter-pan"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		if got := requestName(req); got != tt.want {
+			t.Errorf("requestName(%s %s) = %q, want %q", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CopyOptions configure how resources are copied between APIs.
+type CopyOptions struct {
+	// Recursive copies the descendants of the resource as well.
+	Recursive bool
+	// Parent, if set, replaces the parent path of the copied resource,
+	// e.g. to copy publishers/acme/books/foo to publishers/prod/books/foo.
+	Parent string
+	// Plan shows the changes that would be made, without making them.
+	Plan bool
+	// Yes skips the confirmation required by a protected destination API.
+	Yes bool
+}
+
+// Copy reads the resource at path from src, stripping the fields managed by
+// the server, and creates or updates it in dst.
+func Copy(src, dst *ServiceCommand, path string, opts CopyOptions) (*Result, error) {
+	path = strings.Trim(path, "/")
+	var manifests []*manifest
+	if opts.Recursive {
+		m, err := src.collectTree(path)
+		if err != nil {
+			return nil, err
+		}
+		manifests = m
+	} else {
+		m, err := src.collectResource(path)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			manifests = append(manifests, m)
+		}
+	}
+
+	if opts.Parent != "" {
+		oldParent := parentPath(path)
+		if oldParent == "" {
+			return nil, fmt.Errorf("%s is a top-level resource, and has no parent to rewrite", path)
+		}
+		newParent := strings.Trim(opts.Parent, "/")
+		for _, m := range manifests {
+			m.Path = newParent + strings.TrimPrefix(m.Path, oldParent)
+		}
+	}
+
+	actions, err := dst.planApply(manifests, false)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Plan {
+		return formatPlan(actions), nil
+	}
+	for _, a := range actions {
+		if a.Kind == actionUnchanged {
+			continue
+		}
+		if err := dst.Policy.check(a.Kind, true); err != nil {
+			return nil, fmt.Errorf("unable to %s %s: %v", a.Kind, a.Path, err)
+		}
+	}
+	return dst.executeApply(actions, opts.Yes)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		opts          CopyOptions
+		wantMutations []string
+		wantErr       string
+	}{
+		{
+			name: "single resource",
+			path: "publishers/acme/books/a",
+			opts: CopyOptions{},
+			wantMutations: []string{
+				"POST publishers/acme/books?id=a",
+			},
+		},
+		{
+			name: "recursive",
+			path: "publishers/acme",
+			opts: CopyOptions{Recursive: true},
+			wantMutations: []string{
+				// the publisher already exists, unchanged.
+				"POST publishers/acme/books?id=a",
+				"POST publishers/acme/books/a/editions?id=1",
+			},
+		},
+		{
+			name: "rewrite parent",
+			path: "publishers/acme/books/a",
+			opts: CopyOptions{Recursive: true, Parent: "publishers/prod"},
+			wantMutations: []string{
+				"POST publishers/prod/books?id=a",
+				"POST publishers/prod/books/a/editions?id=1",
+			},
+		},
+		{
+			name:          "plan",
+			path:          "publishers/acme",
+			opts:          CopyOptions{Recursive: true, Plan: true},
+			wantMutations: []string{},
+		},
+		{
+			name:    "rewrite parent of top-level resource",
+			path:    "publishers/acme",
+			opts:    CopyOptions{Parent: "publishers/prod"},
+			wantErr: "top-level resource",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, srcServer := newBookstoreService(t)
+			srcServer.put("publishers/acme", map[string]interface{}{"description": "ACME"})
+			srcServer.put("publishers/acme/books/a", map[string]interface{}{"title": "A", "create_time": "2024-01-01"})
+			srcServer.put("publishers/acme/books/a/editions/1", map[string]interface{}{"displayname": "First"})
+
+			dst, dstServer := newBookstoreService(t)
+			dstServer.put("publishers/acme", map[string]interface{}{"description": "ACME"})
+			dstServer.put("publishers/prod", map[string]interface{}{})
+			dstServer.requests = nil
+
+			_, err := Copy(src, dst, tt.path, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Copy() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Copy() error = %v", err)
+			}
+			if m := dstServer.mutations(); !reflect.DeepEqual(m, tt.wantMutations) {
+				t.Errorf("Copy() mutations = %v, want %v", m, tt.wantMutations)
+			}
+			if m := srcServer.mutations(); len(m) != 0 {
+				t.Errorf("Copy() mutated the source: %v", m)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aep-dev/aep-lib-go/pkg/constants"
+)
+
+// deleteRecursive deletes the resource at path and all of its descendants.
+// If the delete method of the resource accepts the AEP-135 force parameter,
+// the server deletes the descendants itself. Otherwise they are discovered
+// by listing the child collections of every resource, and deleted leaf-first.
+func (s *ServiceCommand) deleteRecursive(path string, yes bool, concurrency int) (*Result, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1, got %d", concurrency)
+	}
+	path = strings.Trim(path, "/")
+	if hasParameter(s.operationForPath(http.MethodDelete, path), constants.FIELD_FORCE_NAME) {
+		question := fmt.Sprintf("Delete %s and all of its descendants?", path)
+		if err := s.confirmChange(question, path, true, yes); err != nil {
+			return nil, err
+		}
+		result, _, err := s.doJSON(http.MethodDelete, fmt.Sprintf("%s?%s=true", path, constants.FIELD_FORCE_NAME), nil)
+		return result, err
+	}
+
+	levels, err := s.deletionLevels(path)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	var plan strings.Builder
+	for _, level := range levels {
+		total += len(level)
+		for _, p := range level {
+			plan.WriteString(fmt.Sprintf("  %s\n", p))
+		}
+	}
+	plan.WriteString(fmt.Sprintf("%d resources will be deleted.", total))
+	if s.DryRun {
+		return &Result{Output: plan.String()}, nil
+	}
+	if err := s.confirmChange(plan.String()+"\nProceed?", path, true, yes); err != nil {
+		return nil, err
+	}
+
+	var output strings.Builder
+	deleted := 0
+	for _, level := range levels {
+		errs := s.deleteAll(level, concurrency)
+		failed := 0
+		for i, p := range level {
+			if errs[i] != nil {
+				failed++
+				output.WriteString(fmt.Sprintf("failed  %s: %v\n", p, errs[i]))
+				continue
+			}
+			deleted++
+			output.WriteString(fmt.Sprintf("deleted %s\n", p))
+		}
+		// parents can not be deleted while their children still exist.
+		if failed > 0 {
+			output.WriteString(fmt.Sprintf("Deleted %d of %d resources.", deleted, total))
+			return &Result{Output: output.String()}, fmt.Errorf("unable to delete %d resources, stopped before deleting their ancestors", failed)
+		}
+	}
+	output.WriteString(fmt.Sprintf("Deleted %d resources.", deleted))
+	return &Result{Output: output.String()}, nil
+}
+
+// deletionLevels returns the paths of the resource at path and its
+// descendants, grouped by depth, deepest first. Paths within a level do not
+// depend on each other and can be deleted in parallel.
+func (s *ServiceCommand) deletionLevels(path string) ([][]string, error) {
+	manifests, err := s.collectTree(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		// nothing was fetched during a dry run.
+		return [][]string{{path}}, nil
+	}
+	byDepth := map[int][]string{}
+	depths := []int{}
+	for _, m := range manifests {
+		r, ok := s.API.Resources[m.Type]
+		if !ok || r.Methods.Delete == nil {
+			return nil, fmt.Errorf("unable to delete %s: resource %q does not support delete", m.Path, m.Type)
+		}
+		d := resourceDepth(m.Path)
+		if _, ok := byDepth[d]; !ok {
+			depths = append(depths, d)
+		}
+		byDepth[d] = append(byDepth[d], m.Path)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+	levels := [][]string{}
+	for _, d := range depths {
+		sort.Strings(byDepth[d])
+		levels = append(levels, byDepth[d])
+	}
+	return levels, nil
+}
+
+// deleteAll deletes the resources at the given paths, running up to
+// concurrency requests in parallel. The returned errors are in the order of
+// the paths.
+func (s *ServiceCommand) deleteAll(paths []string, concurrency int) []error {
+	errs := make([]error, len(paths))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				result, _, err := s.doJSON(http.MethodDelete, paths[i], nil)
+				if err == nil && result != nil && result.StatusCode/100 != 2 {
+					err = responseError(fmt.Sprintf("delete %s", paths[i]), result)
+				}
+				errs[i] = err
+			}
+		}()
+	}
+	for i := range paths {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+func putBookstoreTree(f *fakeServer) {
+	f.put("publishers/acme", nil)
+	f.put("publishers/acme/books/a", nil)
+	f.put("publishers/acme/books/a/editions/1", nil)
+	f.put("publishers/acme/books/a/editions/2", nil)
+	f.put("publishers/acme/books/b", nil)
+	f.put("publishers/other", nil)
+}
+
+func TestDeleteRecursive(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	putBookstoreTree(f)
+	var asked string
+	svc.prompt = func(question string) (string, error) {
+		asked = question
+		return "y", nil
+	}
+
+	result, err := svc.Execute([]string{"publisher", "delete", "acme", "--recursive", "--concurrency", "2"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	wantPlan := strings.Join([]string{
+		"  publishers/acme/books/a/editions/1",
+		"  publishers/acme/books/a/editions/2",
+		"  publishers/acme/books/a",
+		"  publishers/acme/books/b",
+		"  publishers/acme",
+		"5 resources will be deleted.",
+		"Proceed? [y/N]: ",
+	}, "\n")
+	if asked != wantPlan {
+		t.Errorf("prompt =\n%s\nwant\n%s", asked, wantPlan)
+	}
+	if !strings.HasSuffix(result.Output, "Deleted 5 resources.") {
+		t.Errorf("Execute() output = %q", result.Output)
+	}
+	// deletes within a level may run in any order, but levels are sequential.
+	m := f.mutations()
+	if len(m) != 5 {
+		t.Fatalf("mutations = %v, want 5 deletes", m)
+	}
+	sort.Strings(m[:2])
+	sort.Strings(m[2:4])
+	wantMutations := []string{
+		"DELETE publishers/acme/books/a/editions/1",
+		"DELETE publishers/acme/books/a/editions/2",
+		"DELETE publishers/acme/books/a",
+		"DELETE publishers/acme/books/b",
+		"DELETE publishers/acme",
+	}
+	if !reflect.DeepEqual(m, wantMutations) {
+		t.Errorf("mutations = %v, want %v", m, wantMutations)
+	}
+	if _, ok := f.get("publishers/other"); !ok {
+		t.Error("publishers/other was deleted")
+	}
+}
+
+func TestDeleteRecursive_Declined(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	putBookstoreTree(f)
+	svc.prompt = func(question string) (string, error) {
+		return "n", nil
+	}
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme", "--recursive"}); err != errCancelled {
+		t.Fatalf("Execute() error = %v, want %v", err, errCancelled)
+	}
+	if m := f.mutations(); len(m) != 0 {
+		t.Errorf("declined delete made mutations: %v", m)
+	}
+}
+
+func TestDeleteRecursive_NotInteractive(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	putBookstoreTree(f)
+	svc.prompt = func(question string) (string, error) {
+		return "", errNotInteractive
+	}
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme", "--recursive"}); err != errNotInteractive {
+		t.Errorf("Execute() error = %v, want %v", err, errNotInteractive)
+	}
+	if m := f.mutations(); len(m) != 0 {
+		t.Errorf("unconfirmed delete made mutations: %v", m)
+	}
+
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme", "--recursive", "--yes"}); err != nil {
+		t.Errorf("Execute() with --yes error = %v", err)
+	}
+	if _, ok := f.get("publishers/acme"); ok {
+		t.Error("publishers/acme was not deleted")
+	}
+}
+
+func TestDeleteRecursive_Force(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	putBookstoreTree(f)
+	svc.OpenAPI = &openapi.OpenAPI{
+		Paths: map[string]*openapi.PathItem{
+			"/v1/publishers/{publisher}": {
+				Delete: &openapi.Operation{
+					Parameters: []openapi.Parameter{{Name: "force", In: "query"}},
+				},
+			},
+		},
+	}
+	_, err := svc.Execute([]string{"publisher", "delete", "acme", "--recursive", "--yes"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if m := f.mutations(); !reflect.DeepEqual(m, []string{"DELETE publishers/acme?force=true"}) {
+		t.Errorf("mutations = %v, want a single forced delete", m)
+	}
+	if _, ok := f.get("publishers/acme/books/a/editions/1"); ok {
+		t.Error("descendants were not deleted")
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+const (
+	diffFormatUnified = "unified"
+	diffFormatFields  = "fields"
+
+	// the number of unchanged lines shown around each change in a unified diff.
+	diffContextLines = 3
+
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// stripReadOnlyFields returns a copy of data without the fields that the
+// schema marks as read-only or output-only, recursing into nested objects.
+func stripReadOnlyFields(data map[string]interface{}, schema *openapi.Schema) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range data {
+		if schema == nil {
+			result[k] = v
+			continue
+		}
+		prop, ok := schema.Properties[k]
+		if !ok {
+			result[k] = v
+			continue
+		}
+		if isReadOnly(prop) {
+			continue
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(prop.Properties) > 0 {
+			v = stripReadOnlyFields(nested, &prop)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// isReadOnly returns true if the field can not be set by the client.
+func isReadOnly(s openapi.Schema) bool {
+	if s.ReadOnly {
+		return true
+	}
+	if s.XAEPField != nil {
+		for _, b := range s.XAEPField.Behavior {
+			if b == "OUTPUT_ONLY" || b == "FIELD_BEHAVIOR_OUTPUT_ONLY" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// diffResources compares the live resource with the local one, and returns
+// a human-readable diff in the requested format. The returned diff is empty
+// if the two are equal.
+func diffResources(live, local map[string]interface{}, format string, color bool) (string, error) {
+	switch format {
+	case diffFormatUnified, "":
+		return unifiedDiff(live, local, color)
+	case diffFormatFields:
+		return fieldDiff(live, local, color), nil
+	default:
+		return "", fmt.Errorf("unknown diff format %q, expected one of: %s, %s", format, diffFormatUnified, diffFormatFields)
+	}
+}
+
+func unifiedDiff(live, local map[string]interface{}, color bool) (string, error) {
+	liveJSON, err := json.MarshalIndent(live, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal live resource: %v", err)
+	}
+	localJSON, err := json.MarshalIndent(local, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal local resource: %v", err)
+	}
+	a := strings.Split(string(liveJSON), "\n")
+	b := strings.Split(string(localJSON), "\n")
+	ops := diffLines(a, b)
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+
+	var out strings.Builder
+	out.WriteString(colorize("--- live", colorRed, color) + "\n")
+	out.WriteString(colorize("+++ local", colorGreen, color) + "\n")
+	for _, h := range hunks(ops) {
+		out.WriteString(colorize(h.header(), colorCyan, color) + "\n")
+		for _, op := range h.ops {
+			line := string(op.kind) + op.line
+			switch op.kind {
+			case '-':
+				line = colorize(line, colorRed, color)
+			case '+':
+				line = colorize(line, colorGreen, color)
+			}
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.String(), nil
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// 1-indexed line numbers in the live and local documents.
+	aLine, bLine int
+}
+
+// diffLines computes a line-based diff using the longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	ops := []diffOp{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i + 1, j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i + 1, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j + 1})
+			j++
+		}
+	}
+	return ops
+}
+
+type hunk struct {
+	ops []diffOp
+}
+
+func (h hunk) header() string {
+	aStart, bStart, aCount, bCount := 0, 0, 0, 0
+	for _, op := range h.ops {
+		if op.kind != '+' {
+			if aCount == 0 {
+				aStart = op.aLine
+			}
+			aCount++
+		}
+		if op.kind != '-' {
+			if bCount == 0 {
+				bStart = op.bLine
+			}
+			bCount++
+		}
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", aStart, aCount, bStart, bCount)
+}
+
+// hunks groups the changes into hunks, keeping a few lines of context
+// around each change.
+func hunks(ops []diffOp) []hunk {
+	var result []hunk
+	start, end := -1, -1
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		lo := max(0, i-diffContextLines)
+		hi := min(len(ops), i+diffContextLines+1)
+		if start != -1 && lo > end {
+			result = append(result, hunk{ops[start:end]})
+			start = -1
+		}
+		if start == -1 {
+			start = lo
+		}
+		end = hi
+	}
+	if start != -1 {
+		result = append(result, hunk{ops[start:end]})
+	}
+	return result
+}
+
+// fieldDiff lists the fields that differ between the live and local
+// resource, using dotted paths for nested fields.
+func fieldDiff(live, local map[string]interface{}, color bool) string {
+	a := map[string]interface{}{}
+	b := map[string]interface{}{}
+	flatten("", live, a)
+	flatten("", local, b)
+
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out strings.Builder
+	for _, k := range sorted {
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inA:
+			out.WriteString(colorize(fmt.Sprintf("+ %s: %s", k, toJSON(bv)), colorGreen, color) + "\n")
+		case !inB:
+			out.WriteString(colorize(fmt.Sprintf("- %s: %s", k, toJSON(av)), colorRed, color) + "\n")
+		case !reflect.DeepEqual(av, bv):
+			out.WriteString(colorize(fmt.Sprintf("~ %s: %s -> %s", k, toJSON(av), toJSON(bv)), colorCyan, color) + "\n")
+		}
+	}
+	return out.String()
+}
+
+// flatten converts nested objects into a map keyed by dotted field paths.
+// Arrays are compared as a whole.
+func flatten(prefix string, data map[string]interface{}, into map[string]interface{}) {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(key, nested, into)
+			continue
+		}
+		into[key] = v
+	}
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func colorize(s, color string, enabled bool) string {
+	if !enabled {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// useColor returns true if output written to stdout should be colorized.
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+func TestStripReadOnlyFields(t *testing.T) {
+	schema := &openapi.Schema{
+		Properties: map[string]openapi.Schema{
+			"path":  {Type: "string", ReadOnly: true},
+			"title": {Type: "string"},
+			"create_time": {
+				Type:      "string",
+				XAEPField: &openapi.XAEPField{Behavior: []string{"FIELD_BEHAVIOR_OUTPUT_ONLY"}},
+			},
+			"metadata": {
+				Type: "object",
+				Properties: map[string]openapi.Schema{
+					"etag": {Type: "string", ReadOnly: true},
+					"tag":  {Type: "string"},
+				},
+			},
+		},
+	}
+	got := stripReadOnlyFields(map[string]interface{}{
+		"path":        "books/1",
+		"title":       "Peter Pan",
+		"create_time": "2024-01-01T00:00:00Z",
+		"metadata":    map[string]interface{}{"etag": "abc", "tag": "x"},
+		"unknown":     "kept",
+	}, schema)
+	want := map[string]interface{}{
+		"title":    "Peter Pan",
+		"metadata": map[string]interface{}{"tag": "x"},
+		"unknown":  "kept",
+	}
+	if toJSON(got) != toJSON(want) {
+		t.Errorf("stripReadOnlyFields() = %v, want %v", toJSON(got), toJSON(want))
+	}
+}
+
+func TestDiffResources(t *testing.T) {
+	live := map[string]interface{}{
+		"title":  "Peter Pan",
+		"author": "J. M. Barrie",
+		"meta":   map[string]interface{}{"pages": 100},
+	}
+	tests := []struct {
+		name     string
+		local    map[string]interface{}
+		format   string
+		expected []string
+	}{
+		{
+			name:     "equal",
+			local:    map[string]interface{}{"title": "Peter Pan", "author": "J. M. Barrie", "meta": map[string]interface{}{"pages": 100}},
+			format:   diffFormatUnified,
+			expected: nil,
+		},
+		{
+			name:   "unified",
+			local:  map[string]interface{}{"title": "Wendy", "author": "J. M. Barrie", "meta": map[string]interface{}{"pages": 100}},
+			format: diffFormatUnified,
+			expected: []string{
+				"--- live\n+++ local\n",
+				"@@ -3,5 +3,5 @@\n",
+				"-  \"title\": \"Peter Pan\"\n",
+				"+  \"title\": \"Wendy\"\n",
+			},
+		},
+		{
+			name:   "fields",
+			local:  map[string]interface{}{"title": "Wendy", "meta": map[string]interface{}{"pages": 120}, "isbn": "123"},
+			format: diffFormatFields,
+			expected: []string{
+				"- author: \"J. M. Barrie\"\n",
+				"+ isbn: \"123\"\n",
+				"~ meta.pages: 100 -> 120\n",
+				"~ title: \"Peter Pan\" -> \"Wendy\"\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffResources(live, tt.local, tt.format, false)
+			if err != nil {
+				t.Fatalf("diffResources() error = %v", err)
+			}
+			if tt.expected == nil && got != "" {
+				t.Errorf("diffResources() = %q, want no diff", got)
+			}
+			for _, e := range tt.expected {
+				if !strings.Contains(got, e) {
+					t.Errorf("diffResources() = %q, want it to contain %q", got, e)
+				}
+			}
+		})
+	}
+
+	if _, err := diffResources(live, live, "side-by-side", false); err == nil {
+		t.Error("diffResources() expected error for unknown format")
+	}
+}
+
+func TestService_Diff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/users/alice" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"path": "users/alice", "username": "alice", "email": "alice@example.com"}`))
+	}))
+	defer server.Close()
+
+	a := getTestAPI()
+	a.ServerURL = server.URL
+	svc, err := NewServiceCommand(a, nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+
+	same := createTestJSONFile(t, map[string]interface{}{
+		"path":     "users/ignored",
+		"username": "alice",
+		"email":    "alice@example.com",
+	})
+	result, err := svc.Execute([]string{"user", "diff", "alice", "--@data=" + same})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.HasDiff {
+		t.Errorf("Execute() reported a diff for equal resources: %q", result.Output)
+	}
+
+	changed := createTestJSONFile(t, map[string]interface{}{
+		"username": "alice",
+		"email":    "alice@example.org",
+	})
+	result, err = svc.Execute([]string{"user", "diff", "alice", "--@data=" + changed, "--format=fields"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.HasDiff {
+		t.Errorf("Execute() did not report a diff for changed resources")
+	}
+	expected := `~ email: "alice@example.com" -> "alice@example.org"`
+	if result.Output != expected {
+		t.Errorf("Execute() = %q, want %q", result.Output, expected)
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	exportFormatYAML = "yaml"
+	exportFormatJSON = "json"
+)
+
+func (s *ServiceCommand) export(args []string) (*Result, error) {
+	var root string
+	var outputDir string
+	var format string
+	run := false
+
+	c := &cobra.Command{
+		Use:   "export",
+		Short: "Export a tree of resources to manifest files",
+		Long: "Export a resource and all of its descendants, writing one manifest file per resource.\n" +
+			"Without --root, every resource in the API is exported. The files can be restored with import.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			run = true
+		},
+	}
+	c.Flags().StringVar(&root, "root", "", "Path of the resource to export, e.g. publishers/acme")
+	c.Flags().StringVarP(&outputDir, "output", "o", "", "Directory to write the manifest files to")
+	c.Flags().StringVar(&format, "format", exportFormatYAML, fmt.Sprintf("Manifest file format (%s, %s)", exportFormatYAML, exportFormatJSON))
+	c.MarkFlagRequired("output")
+
+	var stdout strings.Builder
+	c.SetOut(&stdout)
+	c.SetArgs(args)
+	if err := c.Execute(); err != nil {
+		return &Result{Output: stdout.String()}, err
+	}
+	if !run {
+		return &Result{Output: stdout.String()}, nil
+	}
+	if format != exportFormatYAML && format != exportFormatJSON {
+		return nil, fmt.Errorf("unknown format %q, expected one of: %s, %s", format, exportFormatYAML, exportFormatJSON)
+	}
+
+	manifests, err := s.collectTree(strings.Trim(root, "/"))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range manifests {
+		if err := writeManifest(outputDir, m, format); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Output: fmt.Sprintf("Exported %d resources to %s", len(manifests), outputDir)}, nil
+}
+
+// collectTree fetches the resource at root and all of its descendants, by
+// listing every child collection of each resource. If root is empty, all
+// top-level resources and their descendants are collected.
+func (s *ServiceCommand) collectTree(root string) ([]*manifest, error) {
+	manifests := []*manifest{}
+	if root == "" {
+		for _, r := range rootResources(&s.API) {
+			if r.Methods.List == nil {
+				slog.Warn("Skipping resource without a list method", "resource", r.Singular)
+				continue
+			}
+			descendants, err := s.collectCollection("", r)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, descendants...)
+		}
+		return manifests, nil
+	}
+
+	m, err := s.collectResource(root)
+	if err != nil || m == nil {
+		return manifests, err
+	}
+	manifests = append(manifests, m)
+	r, _ := resourceForPath(&s.API, root)
+	descendants, err := s.collectChildren(root, r)
+	if err != nil {
+		return nil, err
+	}
+	return append(manifests, descendants...), nil
+}
+
+// collectResource fetches the resource at path. The returned manifest is nil
+// for dry runs.
+func (s *ServiceCommand) collectResource(path string) (*manifest, error) {
+	r, err := resourceForPath(&s.API, path)
+	if err != nil {
+		return nil, err
+	}
+	result, data, err := s.doJSON(http.MethodGet, path, nil)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	if result.StatusCode/100 != 2 {
+		return nil, responseError(fmt.Sprintf("get %s", path), result)
+	}
+	return newManifest(r, path, data), nil
+}
+
+// collectChildren collects the descendants of the resource at path.
+func (s *ServiceCommand) collectChildren(path string, r *api.Resource) ([]*manifest, error) {
+	manifests := []*manifest{}
+	for _, child := range childResources(&s.API, r) {
+		if child.Methods.List == nil {
+			slog.Warn("Skipping resource without a list method", "resource", child.Singular, "parent", path)
+			continue
+		}
+		descendants, err := s.collectCollection(path, child)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, descendants...)
+	}
+	return manifests, nil
+}
+
+// collectCollection collects every resource in the collection of r under
+// the parent path, and their descendants.
+func (s *ServiceCommand) collectCollection(parent string, r *api.Resource) ([]*manifest, error) {
+	collection := childCollectionPath(parent, r)
+	items, err := s.listAll(collection)
+	if err != nil {
+		return nil, err
+	}
+	manifests := []*manifest{}
+	for _, item := range items {
+		p, err := itemPath(collection, item)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, newManifest(r, p, item))
+		descendants, err := s.collectChildren(p, r)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, descendants...)
+	}
+	return manifests, nil
+}
+
+func newManifest(r *api.Resource, path string, data map[string]interface{}) *manifest {
+	return &manifest{
+		Type: r.Singular,
+		Path: path,
+		Data: stripReadOnlyFields(data, r.Schema),
+	}
+}
+
+// writeManifest writes the manifest to a file mirroring the resource path,
+// e.g. publishers/acme/books/peter-pan.yaml.
+func writeManifest(dir string, m *manifest, format string) error {
+	var b []byte
+	var err error
+	if format == exportFormatJSON {
+		b, err = json.MarshalIndent(m, "", "  ")
+	} else {
+		b, err = yaml.Marshal(m)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %v", m.Path, err)
+	}
+	p := filepath.Join(dir, filepath.FromSlash(m.Path)+"."+format)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("unable to create directory for %s: %v", p, err)
+	}
+	if err := os.WriteFile(p, b, 0644); err != nil {
+		return fmt.Errorf("unable to write %s: %v", p, err)
+	}
+	return nil
+}
+
+func (s *ServiceCommand) importManifests(args []string) (*Result, error) {
+	var inputs []string
+	var plan bool
+	var yes bool
+	run := false
+
+	c := &cobra.Command{
+		Use:   "import",
+		Short: "Import resources from manifest files written by export",
+		Long: "Recreate the resources in manifest files written by export. Parents are created before\n" +
+			"their children, and resources that already exist are updated.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			run = true
+		},
+	}
+	c.Flags().StringArrayVarP(&inputs, "input", "i", []string{}, "Manifest file or directory of manifest files to import")
+	c.Flags().BoolVar(&plan, "plan", false, "Show the changes that would be made, without making them")
+	addYesFlag(c, &yes)
+	c.MarkFlagRequired("input")
+
+	var stdout strings.Builder
+	c.SetOut(&stdout)
+	c.SetArgs(args)
+	if err := c.Execute(); err != nil {
+		return &Result{Output: stdout.String()}, err
+	}
+	if !run {
+		return &Result{Output: stdout.String()}, nil
+	}
+
+	manifests, err := readManifests(inputs)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.planApply(manifests, false)
+	if err != nil {
+		return nil, err
+	}
+	if plan {
+		return formatPlan(actions), nil
+	}
+	return s.executeApply(actions, yes)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	src, srcServer := newBookstoreService(t)
+	srcServer.put("publishers/acme", map[string]interface{}{"description": "ACME"})
+	srcServer.put("publishers/acme/books/a", map[string]interface{}{"title": "A", "create_time": "2024-01-01"})
+	srcServer.put("publishers/acme/books/b", map[string]interface{}{"title": "B"})
+	srcServer.put("publishers/acme/books/c", map[string]interface{}{"title": "C"})
+	srcServer.put("publishers/acme/books/a/editions/1", map[string]interface{}{"displayname": "First"})
+	srcServer.put("publishers/other", map[string]interface{}{"description": "Other"})
+
+	dir := t.TempDir()
+	result, err := src.Execute([]string{"export", "--root", "publishers/acme", "-o", dir})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "Exported 5 resources to "+dir {
+		t.Errorf("Execute() output = %q", result.Output)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "publishers", "acme", "books", "a.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read exported file: %v", err)
+	}
+	want := "type: book\npath: publishers/acme/books/a\ndata:\n    title: A\n"
+	if string(b) != want {
+		t.Errorf("exported file = %q, want %q", string(b), want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "publishers", "other.yaml")); err == nil {
+		t.Error("export wrote a resource outside of the root")
+	}
+
+	dst, dstServer := newBookstoreService(t)
+	result, err = dst.Execute([]string{"import", "-i", dir})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.HasSuffix(result.Output, "Apply complete: 5 created, 0 updated, 0 deleted, 0 unchanged.") {
+		t.Errorf("Execute() output = %q", result.Output)
+	}
+	wantMutations := []string{
+		"POST publishers?id=acme",
+		"POST publishers/acme/books?id=a",
+		"POST publishers/acme/books?id=b",
+		"POST publishers/acme/books?id=c",
+		"POST publishers/acme/books/a/editions?id=1",
+	}
+	if m := dstServer.mutations(); !reflect.DeepEqual(m, wantMutations) {
+		t.Errorf("import mutations = %v, want %v", m, wantMutations)
+	}
+	if e, _ := dstServer.get("publishers/acme/books/a/editions/1"); e["displayname"] != "First" {
+		t.Errorf("imported edition = %v", e)
+	}
+}
+
+func TestExport_AllResourcesAsJSON(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	f.put("publishers/acme", map[string]interface{}{"description": "ACME"})
+	f.put("publishers/other", map[string]interface{}{"description": "Other"})
+
+	dir := t.TempDir()
+	if _, err := svc.Execute([]string{"export", "-o", dir, "--format", "json"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	manifests, err := readManifests([]string{dir})
+	if err != nil {
+		t.Fatalf("readManifests() error = %v", err)
+	}
+	if len(manifests) != 2 {
+		t.Errorf("exported %d manifests, want 2", len(manifests))
+	}
+
+	if _, err := svc.Execute([]string{"export", "-o", dir, "--root", "publishers/missing"}); err == nil {
+		t.Error("Execute() expected error for missing root")
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+// fakeServer is an in-memory AEP API, storing resources by path.
+type fakeServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	resources map[string]map[string]interface{}
+	// requests records every request as "METHOD path?query".
+	requests []string
+	// pageSize is the maximum number of results returned per list page.
+	pageSize int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	f := &fakeServer{resources: map[string]map[string]interface{}{}, pageSize: 2}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// put stores a resource with the given path and data.
+func (f *fakeServer) put(path string, data map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := map[string]interface{}{"path": path}
+	for k, v := range data {
+		r[k] = v
+	}
+	f.resources[path] = r
+}
+
+func (f *fakeServer) get(path string) (map[string]interface{}, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.resources[path]
+	return r, ok
+}
+
+// mutations returns the recorded requests that are not GETs.
+func (f *fakeServer) mutations() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := []string{}
+	for _, r := range f.requests {
+		if !strings.HasPrefix(r, "GET ") {
+			m = append(m, r)
+		}
+	}
+	return m
+}
+
+func (f *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	record := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/")
+	if r.URL.RawQuery != "" {
+		record += "?" + r.URL.RawQuery
+	}
+	f.requests = append(f.requests, record)
+
+	path := strings.Trim(r.URL.Path, "/")
+	segments := strings.Split(path, "/")
+	isCollection := len(segments)%2 == 1
+	var body map[string]interface{}
+	if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+		if err := json.Unmarshal(b, &body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && isCollection:
+		f.list(w, r, path)
+	case r.Method == http.MethodGet:
+		f.getResource(w, path)
+	case r.Method == http.MethodPost && isCollection:
+		f.create(w, r, path, body)
+	case r.Method == http.MethodPatch && !isCollection:
+		res, ok := f.resources[path]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "not found"})
+			return
+		}
+		mergePatch(res, body)
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodDelete && !isCollection:
+		if _, ok := f.resources[path]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "not found"})
+			return
+		}
+		force := r.URL.Query().Get("force") == "true"
+		for p := range f.resources {
+			if strings.HasPrefix(p, path+"/") {
+				if !force {
+					writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": "resource has children"})
+					return
+				}
+				delete(f.resources, p)
+			}
+		}
+		delete(f.resources, path)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"message": "method not allowed"})
+	}
+}
+
+func (f *fakeServer) getResource(w http.ResponseWriter, path string) {
+	res, ok := f.resources[path]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (f *fakeServer) list(w http.ResponseWriter, r *http.Request, collection string) {
+	if parent := parentPath(collection + "/x"); parent != "" {
+		if _, ok := f.resources[parent]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "parent not found"})
+			return
+		}
+	}
+	paths := []string{}
+	for p := range f.resources {
+		if collectionPath(p) == collection {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	start, _ := strconv.Atoi(r.URL.Query().Get("page_token"))
+	end := min(len(paths), start+f.pageSize)
+	results := []interface{}{}
+	for _, p := range paths[start:end] {
+		results = append(results, f.resources[p])
+	}
+	resp := map[string]interface{}{"results": results}
+	if end < len(paths) {
+		resp["next_page_token"] = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (f *fakeServer) create(w http.ResponseWriter, r *http.Request, collection string, body map[string]interface{}) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		id = fmt.Sprintf("generated-%d", len(f.resources)+1)
+	}
+	if parent := parentPath(collection + "/" + id); parent != "" {
+		if _, ok := f.resources[parent]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "parent not found"})
+			return
+		}
+	}
+	path := collection + "/" + id
+	if _, ok := f.resources[path]; ok {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"message": "already exists"})
+		return
+	}
+	res := map[string]interface{}{}
+	for k, v := range body {
+		res[k] = v
+	}
+	res["path"] = path
+	f.resources[path] = res
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// getBookstoreAPI returns an API with a publisher > book > book-edition
+// hierarchy, backed by the given server.
+func getBookstoreAPI(serverURL string) *api.API {
+	methods := func() api.Methods {
+		return api.Methods{
+			Get:    &api.GetMethod{},
+			List:   &api.ListMethod{},
+			Create: &api.CreateMethod{SupportsUserSettableCreate: true},
+			Update: &api.UpdateMethod{},
+			Delete: &api.DeleteMethod{},
+		}
+	}
+	a := &api.API{
+		Name:      "bookstore",
+		ServerURL: serverURL,
+		Resources: map[string]*api.Resource{
+			"publisher": {
+				Singular: "publisher",
+				Plural:   "publishers",
+				Parents:  []string{},
+				Schema: &openapi.Schema{
+					Properties: map[string]openapi.Schema{
+						"description": {Type: "string"},
+					},
+				},
+				Methods: methods(),
+			},
+			"book": {
+				Singular: "book",
+				Plural:   "books",
+				Parents:  []string{"publisher"},
+				Schema: &openapi.Schema{
+					Properties: map[string]openapi.Schema{
+						"title":  {Type: "string"},
+						"author": {Type: "string"},
+						"create_time": {
+							Type:     "string",
+							ReadOnly: true,
+						},
+					},
+				},
+				Methods: methods(),
+			},
+			"book-edition": {
+				Singular: "book-edition",
+				Plural:   "book-editions",
+				Parents:  []string{"book"},
+				Schema: &openapi.Schema{
+					Properties: map[string]openapi.Schema{
+						"displayname": {Type: "string"},
+					},
+				},
+				Methods: methods(),
+			},
+		},
+	}
+	if err := api.AddImplicitFieldsAndValidate(a); err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func newBookstoreService(t *testing.T) (*ServiceCommand, *fakeServer) {
+	t.Helper()
+	f := newFakeServer(t)
+	svc, err := NewServiceCommand(getBookstoreAPI(f.URL), nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+	return svc, f
+}
+
+// mergePatch applies the JSON merge patch (RFC 7386) to the resource.
+func mergePatch(res, patch map[string]interface{}) {
+	for k, v := range patch {
+		nested, isObject := v.(map[string]interface{})
+		current, currentIsObject := res[k].(map[string]interface{})
+		switch {
+		case v == nil:
+			delete(res, k)
+		case isObject && currentIsObject:
+			mergePatch(current, nested)
+		default:
+			res[k] = v
+		}
+	}
+}
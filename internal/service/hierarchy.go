@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+)
+
+// The resource hierarchy is derived from the resource patterns rather than
+// the declared parents, as parents are only populated for resources that are
+// annotated with x-aep-resource.
+
+// isVariable returns true if the pattern segment is a variable, e.g. {book_id}.
+func isVariable(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// patternMatches returns true if the path segments match the pattern
+// segments: literal segments must be equal, variables match any value.
+func patternMatches(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+	for i, p := range pattern {
+		if segments[i] == "" {
+			return false
+		}
+		if !isVariable(p) && p != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceForPath returns the resource whose pattern matches the given
+// resource path, e.g. "publishers/acme/books/peter-pan".
+func resourceForPath(a *api.API, path string) (*api.Resource, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range sortedResources(a) {
+		if patternMatches(r.PatternElems(), segments) {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("no resource found matching path %q", path)
+}
+
+// childResources returns the resources whose pattern is nested directly
+// under the pattern of the given resource.
+func childResources(a *api.API, parent *api.Resource) []*api.Resource {
+	parentPattern := parent.PatternElems()
+	children := []*api.Resource{}
+	for _, r := range sortedResources(a) {
+		pattern := r.PatternElems()
+		if len(pattern) != len(parentPattern)+2 {
+			continue
+		}
+		if sameLiterals(parentPattern, pattern) {
+			children = append(children, r)
+		}
+	}
+	return children
+}
+
+// rootResources returns the resources that have no parent.
+func rootResources(a *api.API) []*api.Resource {
+	roots := []*api.Resource{}
+	for _, r := range sortedResources(a) {
+		if len(r.PatternElems()) == 2 {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// sameLiterals returns true if the literal segments of the shorter pattern
+// are equal to the corresponding segments of the longer one.
+func sameLiterals(short, long []string) bool {
+	for i, p := range short {
+		if isVariable(p) != isVariable(long[i]) {
+			return false
+		}
+		if !isVariable(p) && p != long[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceDepth returns the number of ancestors of a resource path.
+func resourceDepth(path string) int {
+	return len(strings.Split(strings.Trim(path, "/"), "/"))/2 - 1
+}
+
+// parentPath returns the path of the parent of a resource path, or an empty
+// string for top-level resources.
+func parentPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) <= 2 {
+		return ""
+	}
+	return strings.Join(segments[:len(segments)-2], "/")
+}
+
+// collectionPath returns the path of the collection a resource path belongs
+// to, e.g. "publishers/acme/books" for "publishers/acme/books/peter-pan".
+func collectionPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return strings.Join(segments[:len(segments)-1], "/")
+}
+
+// resourceID returns the last segment of a resource path.
+func resourceID(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return segments[len(segments)-1]
+}
+
+// childCollectionPath returns the path of the collection of the child
+// resource under the given parent path.
+func childCollectionPath(parent string, child *api.Resource) string {
+	pattern := child.PatternElems()
+	collection := pattern[len(pattern)-2]
+	if parent == "" {
+		return collection
+	}
+	return parent + "/" + collection
+}
+
+func sortedResources(a *api.API) []*api.Resource {
+	names := make([]string, 0, len(a.Resources))
+	for name := range a.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resources := make([]*api.Resource, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, a.Resources[name])
+	}
+	return resources
+}
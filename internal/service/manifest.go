@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"gopkg.in/yaml.v3"
+)
+
+// manifest declares the desired state of a single resource.
+type manifest struct {
+	// Type is the singular name of the resource, e.g. "book".
+	Type string `json:"type" yaml:"type"`
+	// Path is the full resource path, e.g. "publishers/acme/books/peter-pan".
+	Path string                 `json:"path" yaml:"path"`
+	Data map[string]interface{} `json:"data" yaml:"data"`
+	// the file the manifest was read from.
+	source string
+}
+
+// readManifests reads the manifests from the given files and directories.
+// Directories are walked recursively, and every .json, .yaml and .yml file in
+// them is read. YAML files may contain multiple documents, and JSON files may
+// contain a single manifest or an array of them.
+func readManifests(paths []string) ([]*manifest, error) {
+	manifests := []*manifest{}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read manifests from '%s': %v", p, err)
+		}
+		if !info.IsDir() {
+			m, err := readManifestFile(p)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, m...)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !isManifestFile(path) {
+				return nil
+			}
+			m, err := readManifestFile(path)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, m...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manifests, nil
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func readManifestFile(path string) ([]*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file '%s': %v", path, err)
+	}
+	manifests := []*manifest{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(trimmed, &manifests); err != nil {
+				return nil, fmt.Errorf("invalid JSON in '%s': %v", path, err)
+			}
+		} else {
+			var m manifest
+			if err := json.Unmarshal(trimmed, &m); err != nil {
+				return nil, fmt.Errorf("invalid JSON in '%s': %v", path, err)
+			}
+			manifests = append(manifests, &m)
+		}
+	} else {
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var m manifest
+			err := d.Decode(&m)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid YAML in '%s': %v", path, err)
+			}
+			if m.Type == "" && m.Path == "" && m.Data == nil {
+				// skip empty documents
+				continue
+			}
+			manifests = append(manifests, &m)
+		}
+	}
+	for _, m := range manifests {
+		m.source = path
+		m.Path = strings.Trim(m.Path, "/")
+		// round-trip through JSON, so values compare equal to the
+		// ones decoded from API responses.
+		normalized, err := normalizeJSON(m.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data in '%s': %v", path, err)
+		}
+		m.Data = normalized
+	}
+	return manifests, nil
+}
+
+func normalizeJSON(data map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, err
+	}
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+	return normalized, nil
+}
+
+// resolveManifest validates a manifest and returns the resource it declares.
+func resolveManifest(a *api.API, m *manifest) (*api.Resource, error) {
+	if m.Path == "" {
+		return nil, fmt.Errorf("manifest in '%s' has no path", m.source)
+	}
+	if m.Type == "" {
+		return nil, fmt.Errorf("manifest for %s in '%s' has no type", m.Path, m.source)
+	}
+	r, err := resourceForPath(a, m.Path)
+	if err != nil {
+		return nil, fmt.Errorf("manifest in '%s': %v", m.source, err)
+	}
+	if r.Singular != m.Type {
+		return nil, fmt.Errorf("manifest in '%s' declares type %q, but path %s is a %q", m.source, m.Type, m.Path, r.Singular)
+	}
+	return r, nil
+}
+
+// sortByHierarchy sorts manifests so that parents come before their children.
+func sortByHierarchy(manifests []*manifest) {
+	sort.SliceStable(manifests, func(i, j int) bool {
+		di, dj := resourceDepth(manifests[i].Path), resourceDepth(manifests[j].Path)
+		if di != dj {
+			return di < dj
+		}
+		return manifests[i].Path < manifests[j].Path
+	})
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+// operationForPath returns the operation declared in the OpenAPI definition
+// for the method on the request path, or nil if there is none. The paths in
+// the definition may have a prefix that is part of the server URL, and the
+// request path may have one that is not part of the definition, so trailing
+// segments are matched, and the longest match is returned.
+func (s *ServiceCommand) operationForPath(method, path string) *openapi.Operation {
+	_, op := s.operationPath(method, path)
+	return op
+}
+
+// operationPath returns the path in the OpenAPI definition of the operation
+// operationForPath returns, and the operation, or "" and nil if there is
+// none.
+func (s *ServiceCommand) operationPath(method, path string) (string, *openapi.Operation) {
+	if s.OpenAPI == nil {
+		return "", nil
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var op *openapi.Operation
+	var opPath string
+	longest := 0
+	for p, item := range s.OpenAPI.Paths {
+		if item == nil {
+			continue
+		}
+		template := strings.Split(strings.Trim(p, "/"), "/")
+		n := len(template)
+		if n > len(segments) {
+			// the segments the request path does not have must be a
+			// literal prefix.
+			for _, t := range template[:n-len(segments)] {
+				if isVariable(t) {
+					n = 0
+				}
+			}
+			// AEP paths start with a collection, so the matched
+			// segments must too.
+			if n > 0 && !isVariable(template[n-len(segments)]) {
+				n = len(segments)
+			} else {
+				n = 0
+			}
+		}
+		if n == 0 || n <= longest || !segmentsMatch(template[len(template)-n:], segments[len(segments)-n:]) {
+			continue
+		}
+		var candidate *openapi.Operation
+		switch method {
+		case http.MethodGet:
+			candidate = item.Get
+		case http.MethodPost:
+			candidate = item.Post
+		case http.MethodPatch:
+			candidate = item.Patch
+		case http.MethodPut:
+			candidate = item.Put
+		case http.MethodDelete:
+			candidate = item.Delete
+		}
+		if candidate != nil {
+			op, opPath, longest = candidate, p, n
+		}
+	}
+	return opPath, op
+}
+
+// segmentsMatch returns true if the path segments match the segments of a
+// path template, where variables match any value. Custom methods, such as
+// {book}:archive, match if the method names are equal.
+func segmentsMatch(template, segments []string) bool {
+	for i, t := range template {
+		tv, tMethod, _ := strings.Cut(t, ":")
+		sv, sMethod, _ := strings.Cut(segments[i], ":")
+		if tMethod != sMethod {
+			return false
+		}
+		if !isVariable(tv) && tv != sv {
+			return false
+		}
+	}
+	return true
+}
+
+// hasParameter returns true if the operation declares a query parameter with
+// the given name.
+func hasParameter(op *openapi.Operation, name string) bool {
+	if op == nil {
+		return false
+	}
+	for _, p := range op.Parameters {
+		if p.Name == name && (p.In == "" || p.In == "query") {
+			return true
+		}
+	}
+	return false
+}
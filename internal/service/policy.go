@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// methodAnnotation is the cobra annotation holding the HTTP method a
+// resource command sends.
+const methodAnnotation = "aepcli/method"
+
+// Policy restricts the commands that can be run against an API.
+type Policy struct {
+	// ReadOnly blocks every command that changes resources.
+	ReadOnly bool
+	// AllowedMethods, if not empty, lists the only commands that can be
+	// run, e.g. "get", "list", or the name of a custom method. Commands
+	// that do not change resources, such as "tree" and "export", are only
+	// allowed if they are listed too.
+	AllowedMethods []string
+}
+
+// check returns an error if the policy blocks the named command.
+func (p Policy) check(name string, mutating bool) error {
+	if p.ReadOnly && mutating {
+		return fmt.Errorf("%q is blocked by the read_only policy of this API", name)
+	}
+	if len(p.AllowedMethods) == 0 {
+		return nil
+	}
+	for _, m := range p.AllowedMethods {
+		if strings.EqualFold(strings.TrimPrefix(m, ":"), strings.TrimPrefix(name, ":")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is blocked by the allowed_methods policy of this API, which allows: %s",
+		name, strings.Join(p.AllowedMethods, ", "))
+}
+
+// checkCommand returns an error if the policy blocks the resource command.
+func (p Policy) checkCommand(c *cobra.Command) error {
+	method, ok := c.Annotations[methodAnnotation]
+	if !ok {
+		// help and completion commands.
+		return nil
+	}
+	return p.check(c.Name(), method != http.MethodGet)
+}
+
+// apply hides the subcommands of c that the policy blocks, and returns an
+// error if the command selected by args is blocked, before it runs.
+func (p Policy) apply(c *cobra.Command, args []string) error {
+	for _, sub := range c.Commands() {
+		if p.checkCommand(sub) != nil {
+			sub.Hidden = true
+		}
+	}
+	sub, _, err := c.Find(args)
+	if err != nil {
+		return nil
+	}
+	return p.checkCommand(sub)
+}
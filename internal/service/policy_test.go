@@ -0,0 +1,112 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		args     []string
+		expected string
+	}{
+		{
+			name:   "read_only allows get",
+			policy: Policy{ReadOnly: true},
+			args:   []string{"publisher", "get", "acme"},
+		},
+		{
+			name:     "read_only blocks delete",
+			policy:   Policy{ReadOnly: true},
+			args:     []string{"publisher", "delete", "acme", "--yes"},
+			expected: `"delete" is blocked by the read_only policy of this API`,
+		},
+		{
+			name:     "read_only blocks apply",
+			policy:   Policy{ReadOnly: true},
+			args:     []string{"apply", "-f", "manifests"},
+			expected: `"apply" is blocked by the read_only policy of this API`,
+		},
+		{
+			name:     "blocked before arguments are validated",
+			policy:   Policy{ReadOnly: true},
+			args:     []string{"book", "--publisher", "acme", "create"},
+			expected: `"create" is blocked by the read_only policy of this API`,
+		},
+		{
+			name:   "allowed method",
+			policy: Policy{AllowedMethods: []string{"get", "list"}},
+			args:   []string{"publisher", "list"},
+		},
+		{
+			name:     "method not allowed",
+			policy:   Policy{AllowedMethods: []string{"get", "list"}},
+			args:     []string{"publisher", "update", "acme", "--description=new"},
+			expected: `"update" is blocked by the allowed_methods policy of this API, which allows: get, list`,
+		},
+		{
+			name:     "tree not allowed",
+			policy:   Policy{AllowedMethods: []string{"get", "list"}},
+			args:     []string{"tree", "publishers/acme"},
+			expected: `"tree" is blocked by the allowed_methods policy`,
+		},
+		{
+			name:   "tree allowed when listed",
+			policy: Policy{AllowedMethods: []string{"get", "list", "tree"}},
+			args:   []string{"tree", "publishers/acme"},
+		},
+		{
+			name:     "export not allowed",
+			policy:   Policy{AllowedMethods: []string{"get", "list"}},
+			args:     []string{"export", "-o", "out"},
+			expected: `"export" is blocked by the allowed_methods policy`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, f := newBookstoreService(t)
+			f.put("publishers/acme", nil)
+			svc.Policy = tt.policy
+			_, err := svc.Execute(tt.args)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Execute() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Execute() error = %v, want it to contain %q", err, tt.expected)
+			}
+			if m := f.mutations(); len(m) != 0 {
+				t.Errorf("blocked command made mutations: %v", m)
+			}
+		})
+	}
+}
+
+func TestPolicy_Help(t *testing.T) {
+	svc, _ := newBookstoreService(t)
+	svc.Policy = Policy{ReadOnly: true}
+
+	result, err := svc.Execute([]string{"publisher", "--help"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, hidden := range []string{"create", "update", "delete"} {
+		if strings.Contains(result.Output, "  "+hidden+" ") {
+			t.Errorf("help shows blocked command %q:\n%s", hidden, result.Output)
+		}
+	}
+	for _, shown := range []string{"get", "list", "diff"} {
+		if !strings.Contains(result.Output, "  "+shown+" ") {
+			t.Errorf("help does not show command %q:\n%s", shown, result.Output)
+		}
+	}
+
+	help := svc.PrintHelp()
+	if strings.Contains(help, "apply") || !strings.Contains(help, "export") {
+		t.Errorf("PrintHelp() = %s", help)
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// requestIDParameter is the AEP-155 query parameter servers use to
+// deduplicate requests that change resources.
+const requestIDParameter = "request_id"
+
+// setRequestID adds a generated request_id to requests that change resources,
+// if their operation declares one and they do not have one already. Retries
+// send the same request, so they reuse the ID, and the server applies the
+// change at most once.
+func (s *ServiceCommand) setRequestID(r *http.Request) error {
+	if r.Method == http.MethodGet || r.URL.Query().Get(requestIDParameter) != "" {
+		return nil
+	}
+	if !hasParameter(s.operationForPath(r.Method, r.URL.Path), requestIDParameter) {
+		return nil
+	}
+	id, err := newUUID()
+	if err != nil {
+		return fmt.Errorf("unable to generate a request_id: %v", err)
+	}
+	setQueryParameter(r, requestIDParameter, id)
+	slog.Debug("Generated request_id", "method", r.Method, "path", r.URL.Path, "request_id", id)
+	return nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// requestIDDefinition declares a request_id on creating publishers, and on
+// archiving books, under a path prefix.
+func requestIDDefinition() *openapi.OpenAPI {
+	requestID := []openapi.Parameter{{Name: "request_id", In: "query"}}
+	return &openapi.OpenAPI{
+		Paths: map[string]*openapi.PathItem{
+			"/v1/publishers":                                    {Post: &openapi.Operation{Parameters: requestID}, Get: &openapi.Operation{}},
+			"/v1/publishers/{publisher}":                        {Get: &openapi.Operation{}, Delete: &openapi.Operation{}},
+			"/v1/publishers/{publisher}/books/{book}:archive":   {Post: &openapi.Operation{Parameters: requestID}},
+			"/v1/publishers/{publisher}/books/{book}:unarchive": {Post: &openapi.Operation{}},
+		},
+	}
+}
+
+func TestService_RequestID(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	svc.OpenAPI = requestIDDefinition()
+
+	if _, err := svc.Execute([]string{"publisher", "create", "acme", "--yes"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if _, err := svc.Execute([]string{"publisher", "create", "globex", "--yes", "--request-id", "my-id"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if _, err := svc.Execute([]string{"publisher", "delete", "globex", "--yes"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	m := f.mutations()
+	if len(m) != 3 {
+		t.Fatalf("mutations = %v", m)
+	}
+	u, _ := url.Parse(strings.TrimPrefix(m[0], "POST "))
+	if id := u.Query().Get("request_id"); !uuidPattern.MatchString(id) {
+		t.Errorf("create request_id = %q, want a generated UUID", id)
+	}
+	if !strings.Contains(m[1], "request_id=my-id") {
+		t.Errorf("create with --request-id = %q", m[1])
+	}
+	// the delete operation does not declare a request_id.
+	if m[2] != "DELETE publishers/globex" {
+		t.Errorf("delete = %q, want no request_id", m[2])
+	}
+
+	// the flag is only available if the operation declares the parameter.
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme", "--yes", "--request-id", "x"}); err == nil || !strings.Contains(err.Error(), "unknown flag: --request-id") {
+		t.Errorf("Execute() delete with --request-id error = %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "publishers.jsonl")
+	if err := os.WriteFile(file, []byte(`{"id": "initech"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Execute([]string{"publisher", "create", "--from-file", file, "--request-id", "x"}); err == nil || !strings.Contains(err.Error(), "--request-id can not be used with --from-file") {
+		t.Errorf("Execute() with --from-file and --request-id error = %v", err)
+	}
+}
+
+func TestService_RequestID_ReusedOnRetry(t *testing.T) {
+	var mu sync.Mutex
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, r.URL.Query().Get("request_id"))
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"path": "publishers/acme"}`))
+	}))
+	defer server.Close()
+	svc, _ := newRetryingService(t, server.URL, RetryPolicy{Retries: 2})
+	svc.OpenAPI = requestIDDefinition()
+
+	result, err := svc.Execute([]string{"publisher", "create", "acme", "--yes"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want the create to be retried", result.StatusCode)
+	}
+	if len(ids) != 2 || ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("request_ids = %v, want the same ID on the retry", ids)
+	}
+}
+
+func TestOperationForPath(t *testing.T) {
+	svc := &ServiceCommand{OpenAPI: requestIDDefinition()}
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodPost, "/publishers", true},
+		{http.MethodPost, "/api/v1/publishers", true},
+		{http.MethodPost, "/publishers/acme/books/peter-pan:archive", true},
+		{http.MethodPost, "/publishers/acme/books/peter-pan:unarchive", false},
+		{http.MethodPost, "/publishers/acme/books", false},
+		{http.MethodDelete, "/publishers/acme", false},
+	}
+	for _, tt := range tests {
+		if got := hasParameter(svc.operationForPath(tt.method, tt.path), "request_id"); got != tt.want {
+			t.Errorf("operationForPath(%s %s) has request_id = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+	// a collection does not match the variable of a longer path.
+	if op := svc.operationForPath(http.MethodDelete, "/publishers"); op != nil {
+		t.Errorf("operationForPath(DELETE /publishers) = %+v, want none", op)
+	}
+}
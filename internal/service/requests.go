@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"github.com/aep-dev/aep-lib-go/pkg/constants"
+)
+
+// absoluteURL joins a path relative to the API with the server URL.
+func (s *ServiceCommand) absoluteURL(path string) (*url.URL, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/%s", s.API.ServerURL, path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create url: %v", err)
+	}
+	return u, nil
+}
+
+// setQueryParameter sets a query parameter of the request.
+func setQueryParameter(r *http.Request, name, value string) {
+	q := r.URL.Query()
+	q.Set(name, value)
+	r.URL.RawQuery = q.Encode()
+}
+
+// doJSON sends a request to a path relative to the server URL, and decodes
+// the JSON response. The result is nil if the request was not sent because
+// of a dry run.
+func (s *ServiceCommand) doJSON(method, path string, body map[string]interface{}) (*Result, map[string]interface{}, error) {
+	u, err := s.absoluteURL(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var req *http.Request
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error marshalling JSON: %v", err)
+		}
+		req, err = http.NewRequest(method, u.String(), strings.NewReader(string(b)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to create request: %v", err)
+		}
+	} else {
+		req, err = http.NewRequest(method, u.String(), nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to create request: %v", err)
+		}
+	}
+	result, err := s.doRequest(req)
+	if err != nil || result == nil {
+		return result, nil, err
+	}
+	var data map[string]interface{}
+	if result.Output != "" {
+		if err := json.Unmarshal([]byte(result.Output), &data); err != nil {
+			return result, nil, fmt.Errorf("unable to parse response: %v", err)
+		}
+	}
+	return result, data, nil
+}
+
+// responseError returns an error describing an unsuccessful response.
+func responseError(action string, result *Result) error {
+	return fmt.Errorf("unable to %s: status %d: %s", action, result.StatusCode, result.Output)
+}
+
+// listAll lists every resource in a collection, following page tokens.
+func (s *ServiceCommand) listAll(collection string) ([]map[string]interface{}, error) {
+	items := []map[string]interface{}{}
+	pageToken := ""
+	for {
+		p := collection
+		if pageToken != "" {
+			p = fmt.Sprintf("%s?%s=%s", collection, constants.FIELD_PAGE_TOKEN_NAME, url.QueryEscape(pageToken))
+		}
+		result, data, err := s.doJSON(http.MethodGet, p, nil)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			return items, nil
+		}
+		if result.StatusCode/100 != 2 {
+			return nil, responseError(fmt.Sprintf("list %s", collection), result)
+		}
+		items = append(items, listResults(data)...)
+		next, _ := data[constants.FIELD_NEXT_PAGE_TOKEN_NAME].(string)
+		if next == "" {
+			return items, nil
+		}
+		slog.Debug("Fetching next page", "collection", collection, "pageToken", next)
+		pageToken = next
+	}
+}
+
+// listResults returns the resources in a list response. AEP APIs return them
+// in the "results" field, but any array field is accepted.
+func listResults(data map[string]interface{}) []map[string]interface{} {
+	field, ok := data[constants.FIELD_RESULTS_NAME].([]interface{})
+	if !ok {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if a, isArray := data[k].([]interface{}); isArray {
+				field = a
+				break
+			}
+		}
+	}
+	items := []map[string]interface{}{}
+	for _, i := range field {
+		if m, ok := i.(map[string]interface{}); ok {
+			items = append(items, m)
+		}
+	}
+	return items
+}
+
+// itemPath returns the path of a resource returned by a list call.
+func itemPath(collection string, item map[string]interface{}) (string, error) {
+	if p, ok := item[constants.FIELD_PATH_NAME].(string); ok && p != "" {
+		return strings.Trim(p, "/"), nil
+	}
+	if id, ok := item[constants.FIELD_ID_NAME].(string); ok && id != "" {
+		return collection + "/" + id, nil
+	}
+	return "", fmt.Errorf("resource in %s has neither a %q nor an %q field", collection, constants.FIELD_PATH_NAME, constants.FIELD_ID_NAME)
+}
+
+// createPath returns the path to send a create request to for the resource
+// with the given path.
+func createPath(r *api.Resource, path string) (string, error) {
+	if r.Methods.Create == nil {
+		return "", fmt.Errorf("resource %q does not support create", r.Singular)
+	}
+	if !r.Methods.Create.SupportsUserSettableCreate {
+		return "", fmt.Errorf("resource %q does not support user-specified ids, unable to create %s", r.Singular, path)
+	}
+	return fmt.Sprintf("%s?%s=%s", collectionPath(path), constants.FIELD_ID_NAME, url.QueryEscape(resourceID(path))), nil
+}
@@ -13,14 +13,65 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// resourceCommand is the result of parsing the arguments for a resource:
+// the request to send, and any additional work the service command has to
+// perform around it.
+type resourceCommand struct {
+	Request *http.Request
+	// DiffData is set by the diff command to the local resource data the
+	// live resource should be compared against.
+	DiffData   map[string]interface{}
+	DiffFormat string
+	// Bulk is set instead of Request when the command reads its input
+	// from a file with --from-file.
+	Bulk *bulkCommand
+	// Recursive is set by delete --recursive: the resource in Request is
+	// deleted together with all of its descendants.
+	Recursive   bool
+	Concurrency int
+	// Destructive is set for commands that always need to be confirmed,
+	// such as delete.
+	Destructive bool
+	// Yes is set if the user confirmed the command with --yes.
+	Yes bool
+	// ValidateOnly is set by --validate-only: the server only validates the
+	// change, and does not apply it.
+	ValidateOnly bool
+}
+
 func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, string, error) {
+	rc, output, err := parseResourceCommand(r, args, Policy{}, nil)
+	if rc == nil {
+		return nil, output, err
+	}
+	return rc.Request, output, err
+}
+
+// parseResourceCommand parses the arguments of a resource command. The
+// policy restricts the commands that are available, and operation looks up
+// the operation of a method on a path template, to add the flags of the
+// parameters it declares. operation may be nil.
+func parseResourceCommand(r *api.Resource, args []string, policy Policy, operation func(method, path string) *openapi.Operation) (*resourceCommand, string, error) {
+	if operation == nil {
+		operation = func(string, string) *openapi.Operation { return nil }
+	}
 	c := cobra.Command{Use: r.Singular}
 	var err error
 	var req *http.Request
 	var parents []*string
+	rc := &resourceCommand{}
+
+	var parentNames []string
+	var yes bool
+	var requestID string
+	var validateOnly bool
 
 	i := 1
 	patternElems := r.PatternElems()
+	// the path templates of the resource and its collection, used to look
+	// up the parameters their operations declare.
+	itemPath := "/" + strings.Join(patternElems, "/")
+	collectionPath := "/" + strings.Join(patternElems[:len(patternElems)-1], "/")
 	for i < len(patternElems)-1 {
 		p := patternElems[i]
 		flagName := p[1 : len(p)-1] // extract content between braces
@@ -30,12 +81,13 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 		}
 		var flagValue string
 		parents = append(parents, &flagValue)
+		parentNames = append(parentNames, flagName)
 		c.PersistentFlags().StringVar(&flagValue, flagName, "", fmt.Sprintf("The %v of the resource", flagName))
 		c.MarkPersistentFlagRequired(flagName)
 		i += 2
 	}
 
-	withPrefix := func(path string) string {
+	withParents := func(parentValues []string, path string) string {
 		pElems := []string{}
 		for i, p := range patternElems {
 			// last element, we assume this was handled by the caller.
@@ -45,13 +97,32 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 			if i%2 == 0 {
 				pElems = append(pElems, p)
 			} else {
-				pElems = append(pElems, *parents[i/2])
+				pElems = append(pElems, parentValues[i/2])
 			}
 		}
 		prefix := strings.Join(pElems, "/")
 		return fmt.Sprintf("%s%s", prefix, path)
 	}
 
+	parentValues := func() []string {
+		values := []string{}
+		for _, p := range parents {
+			values = append(values, *p)
+		}
+		return values
+	}
+
+	withPrefix := func(path string) string {
+		return withParents(parentValues(), path)
+	}
+
+	// newBulk builds the requests for a command run with --from-file.
+	newBulk := func(f *bulkFlags, spec bulkSpec) {
+		spec.Parents = parentNames
+		spec.ParentDefaults = parentValues()
+		rc.Bulk, err = newBulkCommand(f, spec)
+	}
+
 	if r.Methods.Create != nil {
 		use := "create [id]"
 		args := cobra.ExactArgs(1)
@@ -62,12 +133,33 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 		createArgs := map[string]interface{}{}
 		var dataContent map[string]interface{}
 		createArgs["data"] = &dataContent
+		var createBulk bulkFlags
 
 		createCmd := &cobra.Command{
 			Use:   use,
 			Short: fmt.Sprintf("Create a %v", strings.ToLower(r.Singular)),
-			Args:  args,
+			Args: func(cmd *cobra.Command, a []string) error {
+				if createBulk.fromFile != "" {
+					return cobra.NoArgs(cmd, a)
+				}
+				return args(cmd, a)
+			},
 			Run: func(cmd *cobra.Command, args []string) {
+				if createBulk.fromFile != "" {
+					newBulk(&createBulk, bulkSpec{
+						Method:   "POST",
+						Schema:   r.Schema,
+						Required: r.Schema.Required,
+						NeedsID:  r.Methods.Create.SupportsUserSettableCreate,
+						Path: func(parents []string, id string) string {
+							if id == "" {
+								return withParents(parents, "")
+							}
+							return withParents(parents, fmt.Sprintf("?id=%s", url.QueryEscape(id)))
+						},
+					})
+					return
+				}
 				p := withPrefix("")
 				if r.Methods.Create.SupportsUserSettableCreate {
 					id := args[0]
@@ -82,9 +174,14 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 					slog.Error(fmt.Sprintf("error creating post request: %v", err))
 				}
 			},
+			Annotations: map[string]string{methodAnnotation: "POST"},
 		}
 
 		createCmd.Flags().Var(&DataFlag{&dataContent}, "@data", "Read resource data from JSON file")
+		addBulkFlags(createCmd, &createBulk)
+		addYesFlag(createCmd, &yes)
+		addRequestIDFlag(createCmd, operation(http.MethodPost, collectionPath), &requestID)
+		addValidateOnlyFlag(createCmd, operation(http.MethodPost, collectionPath), &validateOnly)
 
 		addSchemaFlags(createCmd, *r.Schema, createArgs)
 		c.AddCommand(createCmd)
@@ -100,8 +197,31 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 				p := withPrefix(fmt.Sprintf("/%s", id))
 				req, err = http.NewRequest("GET", p, nil)
 			},
+			Annotations: map[string]string{methodAnnotation: "GET"},
 		}
 		c.AddCommand(getCmd)
+
+		var diffDataContent map[string]interface{}
+		var diffFormat string
+		diffCmd := &cobra.Command{
+			Use:   "diff [id]",
+			Short: fmt.Sprintf("Compare a %v with local data", strings.ToLower(r.Singular)),
+			Long: fmt.Sprintf("Compare a live %v with the data in a local file. Read-only fields are ignored.\n"+
+				"Exits with code 1 if there are differences.", strings.ToLower(r.Singular)),
+			Args: cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				id := args[0]
+				p := withPrefix(fmt.Sprintf("/%s", id))
+				req, err = http.NewRequest("GET", p, nil)
+				rc.DiffData = diffDataContent
+				rc.DiffFormat = diffFormat
+			},
+			Annotations: map[string]string{methodAnnotation: "GET"},
+		}
+		diffCmd.Flags().Var(&DataFlag{&diffDataContent}, "@data", "Read resource data to compare against from JSON file")
+		diffCmd.Flags().StringVar(&diffFormat, "format", diffFormatUnified, fmt.Sprintf("Diff output format (%s, %s)", diffFormatUnified, diffFormatFields))
+		diffCmd.MarkFlagRequired("@data")
+		c.AddCommand(diffCmd)
 	}
 
 	if r.Methods.Update != nil {
@@ -110,11 +230,24 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 		var updateDataContent map[string]interface{}
 		updateArgs["data"] = &updateDataContent
 
+		var updateBulk bulkFlags
+
 		updateCmd := &cobra.Command{
 			Use:   "update [id]",
 			Short: fmt.Sprintf("Update a %v", strings.ToLower(r.Singular)),
-			Args:  cobra.ExactArgs(1),
+			Args:  bulkArgs(1, &updateBulk),
 			Run: func(cmd *cobra.Command, args []string) {
+				if updateBulk.fromFile != "" {
+					newBulk(&updateBulk, bulkSpec{
+						Method:  "PATCH",
+						Schema:  r.Schema,
+						NeedsID: true,
+						Path: func(parents []string, id string) string {
+							return withParents(parents, fmt.Sprintf("/%s", id))
+						},
+					})
+					return
+				}
 				id := args[0]
 				p := withPrefix(fmt.Sprintf("/%s", id))
 				jsonBody, err := generateJsonPayload(cmd, updateArgs)
@@ -126,9 +259,14 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 					slog.Error(fmt.Sprintf("error creating patch request: %v", err))
 				}
 			},
+			Annotations: map[string]string{methodAnnotation: "PATCH"},
 		}
 
 		updateCmd.Flags().Var(&DataFlag{&updateDataContent}, "@data", "Read resource data from JSON file")
+		addBulkFlags(updateCmd, &updateBulk)
+		addYesFlag(updateCmd, &yes)
+		addRequestIDFlag(updateCmd, operation(http.MethodPatch, itemPath), &requestID)
+		addValidateOnlyFlag(updateCmd, operation(http.MethodPatch, itemPath), &validateOnly)
 
 		addSchemaFlags(updateCmd, *r.Schema, updateArgs)
 		c.AddCommand(updateCmd)
@@ -136,16 +274,43 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 
 	if r.Methods.Delete != nil {
 
+		var deleteBulk bulkFlags
+		var recursive bool
+
 		deleteCmd := &cobra.Command{
 			Use:   "delete [id]",
 			Short: fmt.Sprintf("Delete a %v", strings.ToLower(r.Singular)),
-			Args:  cobra.ExactArgs(1),
+			Args:  bulkArgs(1, &deleteBulk),
 			Run: func(cmd *cobra.Command, args []string) {
+				rc.Destructive = true
+				if deleteBulk.fromFile != "" && recursive {
+					err = fmt.Errorf("--recursive can not be used with --from-file")
+					return
+				}
+				if deleteBulk.fromFile != "" {
+					newBulk(&deleteBulk, bulkSpec{
+						Method:  "DELETE",
+						NeedsID: true,
+						Path: func(parents []string, id string) string {
+							return withParents(parents, fmt.Sprintf("/%s", id))
+						},
+					})
+					return
+				}
 				id := args[0]
 				p := withPrefix(fmt.Sprintf("/%s", id))
 				req, err = http.NewRequest("DELETE", p, nil)
+				rc.Recursive = recursive
+				rc.Concurrency = deleteBulk.concurrency
 			},
+			Annotations: map[string]string{methodAnnotation: "DELETE"},
 		}
+		deleteCmd.Flags().BoolVar(&recursive, "recursive", false, "Delete the resource and all of its descendants")
+		addYesFlag(deleteCmd, &yes)
+		addRequestIDFlag(deleteCmd, operation(http.MethodDelete, itemPath), &requestID)
+		addValidateOnlyFlag(deleteCmd, operation(http.MethodDelete, itemPath), &validateOnly)
+		addBulkFlags(deleteCmd, &deleteBulk)
+		deleteCmd.Flags().Lookup("concurrency").Usage = "Number of requests to run in parallel with --from-file or --recursive"
 		c.AddCommand(deleteCmd)
 	}
 
@@ -158,18 +323,36 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 				p := withPrefix("")
 				req, err = http.NewRequest("GET", p, nil)
 			},
+			Annotations: map[string]string{methodAnnotation: "GET"},
 		}
 		c.AddCommand(listCmd)
 	}
 	for _, cm := range r.CustomMethods {
 		customArgs := map[string]interface{}{}
 		var customDataContent map[string]interface{}
+		var customBulk bulkFlags
 
 		customCmd := &cobra.Command{
 			Use:   fmt.Sprintf(":%s [id]", cm.Name),
 			Short: fmt.Sprintf("%v a %v", cm.Method, strings.ToLower(r.Singular)),
-			Args:  cobra.ExactArgs(1),
+			Args:  bulkArgs(1, &customBulk),
 			Run: func(cmd *cobra.Command, args []string) {
+				rc.Destructive = cm.Method == "POST"
+				if customBulk.fromFile != "" {
+					spec := bulkSpec{
+						Method:  cm.Method,
+						NeedsID: true,
+						Path: func(parents []string, id string) string {
+							return withParents(parents, fmt.Sprintf("/%s:%s", id, cm.Name))
+						},
+					}
+					if cm.Method == "POST" {
+						spec.Schema = cm.Request
+						spec.Required = cm.Request.Required
+					}
+					newBulk(&customBulk, spec)
+					return
+				}
 				id := args[0]
 				p := withPrefix(fmt.Sprintf("/%s:%s", id, cm.Name))
 				if cm.Method == "POST" {
@@ -182,6 +365,7 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 					req, err = http.NewRequest(cm.Method, p, nil)
 				}
 			},
+			Annotations: map[string]string{methodAnnotation: cm.Method},
 		}
 
 		if cm.Method == "POST" {
@@ -189,15 +373,80 @@ func ExecuteResourceCommand(r *api.Resource, args []string) (*http.Request, stri
 			customCmd.Flags().Var(&DataFlag{&customDataContent}, "@data", "Read resource data from JSON file")
 			addSchemaFlags(customCmd, *cm.Request, customArgs)
 		}
+		addBulkFlags(customCmd, &customBulk)
+		if cm.Method != "GET" {
+			addYesFlag(customCmd, &yes)
+			addRequestIDFlag(customCmd, operation(cm.Method, itemPath+":"+cm.Name), &requestID)
+			addValidateOnlyFlag(customCmd, operation(cm.Method, itemPath+":"+cm.Name), &validateOnly)
+		}
 		c.AddCommand(customCmd)
 	}
+	if err := policy.apply(&c, args); err != nil {
+		return nil, "", err
+	}
 	var stdout strings.Builder
 	c.SetOut(&stdout)
 	c.SetArgs(args)
 	if err := c.Execute(); err != nil {
 		return nil, stdout.String(), err
 	}
-	return req, stdout.String(), err
+	rc.Yes = yes
+	rc.ValidateOnly = validateOnly
+	if requestID != "" && (rc.Bulk != nil || rc.Recursive) {
+		return nil, stdout.String(), fmt.Errorf("--request-id can not be used with --from-file or --recursive, which send many requests")
+	}
+	if validateOnly && rc.Recursive {
+		return nil, stdout.String(), fmt.Errorf("--validate-only can not be used with --recursive")
+	}
+	if rc.Bulk != nil {
+		if validateOnly {
+			for _, b := range rc.Bulk.Requests {
+				if b.Request != nil {
+					setQueryParameter(b.Request, validateOnlyParameter, "true")
+				}
+			}
+		}
+		return rc, stdout.String(), err
+	}
+	if req == nil {
+		return nil, stdout.String(), err
+	}
+	if requestID != "" {
+		setQueryParameter(req, requestIDParameter, requestID)
+	}
+	if validateOnly {
+		setQueryParameter(req, validateOnlyParameter, "true")
+	}
+	rc.Request = req
+	return rc, stdout.String(), err
+}
+
+// addYesFlag adds the flag to skip the confirmation of a command that
+// changes resources.
+func addYesFlag(c *cobra.Command, yes *bool) {
+	c.Flags().BoolVar(yes, "yes", false, "Do not ask for confirmation")
+}
+
+// validateOnlyParameter is the AEP-163 query parameter asking the server to
+// validate a change without applying it.
+const validateOnlyParameter = "validate_only"
+
+// addValidateOnlyFlag adds the flag to only validate a change, if the
+// operation declares the AEP-163 validate_only parameter.
+func addValidateOnlyFlag(c *cobra.Command, op *openapi.Operation, validateOnly *bool) {
+	if !hasParameter(op, validateOnlyParameter) {
+		return
+	}
+	c.Flags().BoolVar(validateOnly, "validate-only", false, "Ask the server to validate the change without applying it")
+}
+
+// addRequestIDFlag adds the flag to set the AEP-155 request_id of a command
+// that changes resources, if the operation declares the request_id parameter.
+func addRequestIDFlag(c *cobra.Command, op *openapi.Operation, requestID *string) {
+	if !hasParameter(op, requestIDParameter) {
+		return
+	}
+	c.Flags().StringVar(requestID, "request-id", "", "The request_id the server uses to deduplicate retries. Generated if the API supports it.")
 }
 
 func addSchemaFlags(c *cobra.Command, schema openapi.Schema, args map[string]interface{}) error {
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// retryBaseWait is the wait before the first retry. It doubles with each
+// retry, up to the maximum wait of the policy.
+const retryBaseWait = 500 * time.Millisecond
+
+// RetryPolicy configures how requests that failed with a transient error are
+// retried.
+type RetryPolicy struct {
+	// Retries is the maximum number of times a request is retried. Requests
+	// are not retried if it is 0.
+	Retries int
+	// MaxWait is the longest aepcli waits before a retry. Servers asking to
+	// wait longer with Retry-After are not retried.
+	MaxWait time.Duration
+}
+
+// retryableStatus are the status codes of transient failures.
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:     true,
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// send sends the request, retrying it according to the retry policy. body is
+// the request body, which is sent again with each retry.
+func (s *ServiceCommand) send(r *http.Request, body string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && r.Body != nil {
+			r.Body = io.NopCloser(strings.NewReader(body))
+		}
+		resp, err := s.Client.Do(r)
+		if attempt >= s.Retry.Retries || !s.safeToRetry(r) || r.Context().Err() != nil {
+			return resp, err
+		}
+		wait, ok := s.Retry.wait(attempt, resp, err)
+		if !ok {
+			return resp, err
+		}
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		slog.Info("Retrying request", "method", r.Method, "url", s.Redact.URL(r.URL), "reason", reason, "wait", wait, "retry", attempt+1)
+		if s.sleep != nil {
+			s.sleep(wait)
+			continue
+		}
+		select {
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+}
+
+// safeToRetry returns whether sending the request again can not apply a change
+// twice: idempotent methods, and mutations sent with an AEP-155 request_id
+// that their operation declares, so the server uses it to deduplicate them.
+func (s *ServiceCommand) safeToRetry(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if r.URL.Query().Get(requestIDParameter) == "" {
+		return false
+	}
+	return hasParameter(s.operationForPath(r.Method, r.URL.Path), requestIDParameter)
+}
+
+// wait returns how long to wait before retrying the attempt, and false if it
+// should not be retried.
+func (p RetryPolicy) wait(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		if !transient(err) {
+			return 0, false
+		}
+	} else if !retryableStatus[resp.StatusCode] {
+		return 0, false
+	}
+	maxWait := p.MaxWait
+	if maxWait <= 0 {
+		maxWait = retryBaseWait
+	}
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return d, d <= maxWait
+		}
+	}
+	backoff := retryBaseWait << attempt
+	if backoff > maxWait || backoff <= 0 {
+		backoff = maxWait
+	}
+	// jitter spreads the retries of concurrent requests.
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), true
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// transient returns whether the error of a request is worth retrying: the
+// connection was refused, reset or timed out.
+func transient(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
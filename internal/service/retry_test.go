@@ -0,0 +1,163 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first failures requests with the status, and
+// answers the others.
+type flakyServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+}
+
+func newFlakyServer(t *testing.T, failures, status int, retryAfter string) *flakyServer {
+	t.Helper()
+	f := &flakyServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests++
+		n := f.requests
+		f.mu.Unlock()
+		if n <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"path": "publishers/acme"}`))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newRetryingService(t *testing.T, url string, retry RetryPolicy) (*ServiceCommand, *[]time.Duration) {
+	t.Helper()
+	svc, err := NewServiceCommand(getBookstoreAPI(url), nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+	svc.Retry = retry
+	waits := &[]time.Duration{}
+	svc.sleep = func(d time.Duration) { *waits = append(*waits, d) }
+	return svc, waits
+}
+
+func TestService_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		retryAfter   string
+		args         []string
+		wantRequests int
+		wantStatus   int
+	}{
+		{"get is retried", 2, http.StatusServiceUnavailable, "", []string{"publisher", "get", "acme"}, 3, http.StatusOK},
+		{"retries are limited", 5, http.StatusTooManyRequests, "", []string{"publisher", "get", "acme"}, 4, http.StatusTooManyRequests},
+		{"client errors are not retried", 1, http.StatusBadRequest, "", []string{"publisher", "get", "acme"}, 1, http.StatusBadRequest},
+		{"create is not retried", 1, http.StatusServiceUnavailable, "", []string{"publisher", "create", "acme", "--yes"}, 1, http.StatusServiceUnavailable},
+		{"delete is retried", 1, http.StatusBadGateway, "", []string{"publisher", "delete", "acme", "--yes"}, 2, http.StatusOK},
+		{"long Retry-After is not waited for", 1, http.StatusServiceUnavailable, "3600", []string{"publisher", "get", "acme"}, 1, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFlakyServer(t, tt.failures, tt.status, tt.retryAfter)
+			svc, _ := newRetryingService(t, server.URL, RetryPolicy{Retries: 3, MaxWait: 10 * time.Second})
+			result, err := svc.Execute(tt.args)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, tt.wantStatus)
+			}
+			if server.requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", server.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestService_Retry_Waits(t *testing.T) {
+	server := newFlakyServer(t, 3, http.StatusServiceUnavailable, "")
+	svc, waits := newRetryingService(t, server.URL, RetryPolicy{Retries: 3, MaxWait: 1500 * time.Millisecond})
+	if _, err := svc.Execute([]string{"publisher", "get", "acme"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// the backoff doubles from 500ms, capped at the maximum wait, and is
+	// jittered down to half of it.
+	limits := []time.Duration{500 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	if len(*waits) != len(limits) {
+		t.Fatalf("waits = %v, want %d", *waits, len(limits))
+	}
+	for i, w := range *waits {
+		if w < limits[i]/2 || w > limits[i] {
+			t.Errorf("wait %d = %v, want between %v and %v", i, w, limits[i]/2, limits[i])
+		}
+	}
+
+	server = newFlakyServer(t, 1, http.StatusTooManyRequests, "2")
+	svc, waits = newRetryingService(t, server.URL, RetryPolicy{Retries: 3, MaxWait: 10 * time.Second})
+	if _, err := svc.Execute([]string{"publisher", "get", "acme"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 2*time.Second {
+		t.Errorf("waits = %v, want the Retry-After of 2s", *waits)
+	}
+}
+
+func TestService_Retry_RequestID(t *testing.T) {
+	server := newFlakyServer(t, 1, http.StatusServiceUnavailable, "")
+	svc, _ := newRetryingService(t, server.URL, RetryPolicy{Retries: 3})
+	svc.OpenAPI = requestIDDefinition()
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/publishers?id=acme&request_id=d1c5e5a0", strings.NewReader(`{"description": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := svc.doRequest(req)
+	if err != nil {
+		t.Fatalf("doRequest() error = %v", err)
+	}
+	if result.StatusCode != http.StatusOK || server.requests != 2 {
+		t.Errorf("StatusCode = %d after %d requests, want a retried create", result.StatusCode, server.requests)
+	}
+}
+
+func TestService_Retry_UndeclaredRequestID(t *testing.T) {
+	server := newFlakyServer(t, 1, http.StatusServiceUnavailable, "")
+	svc, _ := newRetryingService(t, server.URL, RetryPolicy{Retries: 3})
+	svc.OpenAPI = requestIDDefinition()
+	// the operation does not declare a request_id, so the server does not
+	// deduplicate it.
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/publishers/acme/books/peter-pan:unarchive?request_id=d1c5e5a0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := svc.doRequest(req)
+	if err != nil {
+		t.Fatalf("doRequest() error = %v", err)
+	}
+	if result.StatusCode != http.StatusServiceUnavailable || server.requests != 1 {
+		t.Errorf("StatusCode = %d after %d requests, want the custom method not to be retried", result.StatusCode, server.requests)
+	}
+}
+
+func TestService_Retry_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	svc, waits := newRetryingService(t, url, RetryPolicy{Retries: 2})
+	if _, err := svc.Execute([]string{"publisher", "get", "acme"}); err == nil {
+		t.Fatal("Execute() did not fail")
+	}
+	if len(*waits) != 2 {
+		t.Errorf("retried %d times, want 2", len(*waits))
+	}
+}
@@ -2,19 +2,43 @@ package service
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+	"github.com/aep-dev/aepcli/internal/redact"
+	"github.com/aep-dev/aepcli/internal/secret"
+	"github.com/aep-dev/aepcli/internal/timing"
 )
 
+// apiCommands are the commands available next to the resources of an API.
+var apiCommands = []apiCommand{
+	{Name: "apply", Short: "Create or update resources from manifest files", Mutating: true, Run: (*ServiceCommand).apply},
+	{Name: "export", Short: "Export a tree of resources to manifest files", Run: (*ServiceCommand).export},
+	{Name: "import", Short: "Import resources from manifest files written by export", Mutating: true, Run: (*ServiceCommand).importManifests},
+	{Name: "tree", Short: "Show a resource and its descendants", Run: (*ServiceCommand).tree},
+}
+
+// Authenticator adds credentials to the requests sent to an API.
+type Authenticator interface {
+	Authenticate(r *http.Request) error
+}
+
+// operationAuthenticator is an Authenticator whose credentials depend on the
+// operation, e.g. as operations of an OpenAPI definition can declare their
+// own security. path is the path of the operation in the definition.
+type operationAuthenticator interface {
+	AuthenticateOperation(r *http.Request, path string) error
+}
+
 type ServiceCommand struct {
 	API        api.API
 	Headers    map[string]string
@@ -23,32 +47,50 @@ type ServiceCommand struct {
 	Insecure   bool
 	CACertPath string
 	Client     *http.Client
+	// OpenAPI is the definition the API was read from, used to look up
+	// details of operations that are not part of the API, such as the
+	// parameters they accept. It may be nil.
+	OpenAPI *openapi.OpenAPI
+	// Name is the name the API is configured with. Changes to protected APIs
+	// that apply to many resources are confirmed by typing it.
+	Name string
+	// Protected requires every change to be confirmed by typing the path of
+	// the resource it applies to, or Name if it applies to many resources,
+	// such as bulk commands and apply.
+	Protected bool
+	// Policy restricts the commands that can be run.
+	Policy Policy
+	// Auth, if set, authenticates every request sent to the API.
+	Auth Authenticator
+	// Retry configures how requests that failed with a transient error are
+	// retried.
+	Retry RetryPolicy
+	// PrintAs, if set, prints every request as a command or code snippet in
+	// the format, e.g. curl. PrintMasked masks the credentials in it.
+	PrintAs     string
+	PrintMasked bool
+	// Redact hides credentials and sensitive fields in the requests that are
+	// logged and printed. If nil, the default credential headers are
+	// redacted.
+	Redact *redact.Redactor
+	// Timing, if set, is the transport reporting the timings of requests.
+	// Their totals are reported once a command ran.
+	Timing *timing.Transport
+	// prompt replaces the terminal prompt used for confirmations, for tests.
+	prompt func(question string) (string, error)
+	// sleep replaces the wait between retries, for tests.
+	sleep func(time.Duration)
+	// ctx is the context of the command being executed.
+	ctx context.Context
 }
 
+// NewServiceCommand returns the command of the API. Its client verifies
+// server certificates as configured by insecure and caCertPath; a client
+// with more options, made by NewHTTPClient, can replace it.
 func NewServiceCommand(api *api.API, headers map[string]string, dryRun bool, logHTTP bool, insecure bool, caCertPath string) (*ServiceCommand, error) {
-	client := &http.Client{}
-
-	if insecure {
-		slog.Debug("Loading TLS configuration")
-		slog.Debug("TLS certificate verification disabled (insecure mode)")
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client.Transport = tr
-	} else if caCertPath != "" {
-		slog.Debug("Loading TLS configuration")
-		// Load custom CA certificate
-		caCertPool, err := loadCACertificate(caCertPath)
-		if err != nil {
-			return nil, err
-		}
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{RootCAs: caCertPool},
-		}
-		client.Transport = tr
-	} else {
-		// Use default system CA certificates
-		slog.Debug("Using system CA certificates")
+	client, err := NewHTTPClient(ClientOptions{Insecure: insecure, CACertPath: caCertPath})
+	if err != nil {
+		return nil, err
 	}
 
 	return &ServiceCommand{
@@ -62,45 +104,117 @@ func NewServiceCommand(api *api.API, headers map[string]string, dryRun bool, log
 	}, nil
 }
 
+// Execute runs the command given by the arguments.
 func (s *ServiceCommand) Execute(args []string) (*Result, error) {
+	return s.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs the command given by the arguments. Cancelling the
+// context cancels the requests in flight, and those not yet sent.
+func (s *ServiceCommand) ExecuteContext(ctx context.Context, args []string) (*Result, error) {
+	s.ctx = ctx
+	if s.Timing != nil {
+		defer s.Timing.PrintTotals()
+	}
 	if len(args) == 0 || args[0] == "--help" {
-		return &Result{s.PrintHelp(), 0}, nil
+		return &Result{Output: s.PrintHelp()}, nil
+	}
+	if args[0] == "--tree" {
+		return &Result{Output: s.PrintTree()}, nil
 	}
 	resource := args[0]
+	if _, ok := s.API.Resources[resource]; !ok {
+		for _, c := range apiCommands {
+			if c.Name == resource {
+				if err := s.Policy.check(c.Name, c.Mutating); err != nil {
+					return nil, err
+				}
+				return c.Run(s, args[1:])
+			}
+		}
+	}
 	r, err := s.API.GetResource(resource)
 	if err != nil {
 		return nil, fmt.Errorf("%v\n%v", err, s.PrintHelp())
 	}
-	req, output, err := ExecuteResourceCommand(r, args[1:])
+	rc, output, err := parseResourceCommand(r, args[1:], s.Policy, s.operationForPath)
 	if err != nil {
-		return &Result{output, 0}, err
+		return &Result{Output: output}, err
+	}
+	if rc == nil {
+		return &Result{Output: output}, nil
+	}
+	// changes that are only validated do not need to be confirmed.
+	yes := rc.Yes || rc.ValidateOnly
+	if rc.Bulk != nil {
+		question := fmt.Sprintf("Send %d %s requests from %s?", len(rc.Bulk.Requests), rc.Bulk.Method, rc.Bulk.Input)
+		if err := s.confirmChange(question, s.Name, rc.Destructive, yes); err != nil {
+			return nil, err
+		}
+		result, err := s.executeBulk(rc.Bulk)
+		if rc.ValidateOnly && result != nil {
+			result.Output = validateOnlyNote + "\n" + result.Output
+		}
+		return result, err
+	}
+	if rc.Recursive {
+		return s.deleteRecursive(rc.Request.URL.String(), rc.Yes, rc.Concurrency)
 	}
-	if req == nil {
-		return &Result{output, 0}, nil
+	req := rc.Request
+	if req.Method != http.MethodGet {
+		question := fmt.Sprintf("%s %s?", req.Method, req.URL.String())
+		if err := s.confirmChange(question, requestName(req), rc.Destructive, yes); err != nil {
+			return nil, err
+		}
 	}
-	url, err := url.Parse(fmt.Sprintf("%s/%s", s.API.ServerURL, req.URL.String()))
+	url, err := s.absoluteURL(req.URL.String())
 	if err != nil {
-		return nil, fmt.Errorf("unable to create url: %v", err)
+		return nil, err
 	}
 	req.URL = url
 	resp, err := s.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("unable to execute request: %v", err)
 	}
+	if rc.DiffData != nil && resp != nil && resp.StatusCode/100 == 2 {
+		resp, err = s.diff(r, resp, rc.DiffData, rc.DiffFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rc.ValidateOnly && resp != nil {
+		resp.Output = validateOnlyNote + "\n" + resp.Output
+	}
 	if output != "" {
 		resp.Output = output + "\n" + resp.Output
 	}
 	return resp, nil
 }
 
+// validateOnlyNote is printed before the response to a request sent with
+// --validate-only.
+const validateOnlyNote = "Validate only: the server only validated the request, and nothing was changed."
+
+// context returns the context of the command being executed.
+func (s *ServiceCommand) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 func (s *ServiceCommand) doRequest(r *http.Request) (*Result, error) {
+	if err := s.context().Err(); err != nil {
+		return nil, err
+	}
+	r = r.WithContext(s.context())
 	contentType := "application/json"
 	if r.Method == http.MethodPatch {
 		contentType = "application/merge-patch+json"
 	}
 	r.Header.Set("Content-Type", contentType)
-	for k, v := range s.Headers {
-		r.Header.Set(k, v)
+	if err := s.setRequestID(r); err != nil {
+		return nil, err
 	}
 	body := ""
 	if r.Body != nil {
@@ -111,30 +225,103 @@ func (s *ServiceCommand) doRequest(r *http.Request) (*Result, error) {
 		r.Body = io.NopCloser(bytes.NewBuffer(b))
 		body = string(b)
 	}
-	requestLog := fmt.Sprintf("Request: %s %s\n%s", r.Method, r.URL.String(), string(body))
+	requestLog := fmt.Sprintf("Request: %s %s\n%s", r.Method, s.Redact.URL(r.URL), s.Redact.Body(body))
 	slog.Debug(requestLog)
 	if s.LogHTTP {
 		fmt.Println(requestLog)
 	}
+	if s.DryRun && s.PrintAs == "" {
+		slog.Debug("Dry run: not making request")
+		return nil, nil
+	}
+	// headers may refer to secrets, which are only resolved when the
+	// request is sent.
+	for k, v := range s.Headers {
+		resolved, err := secret.Resolve(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve header %s: %v", k, err)
+		}
+		r.Header.Set(k, resolved)
+	}
+	if s.Auth != nil {
+		if err := s.authenticate(r); err != nil {
+			return nil, fmt.Errorf("unable to authenticate: %v", err)
+		}
+	}
+	slog.Debug("Request headers", "headers", s.Redact.Headers(r.Header))
+	if s.PrintAs != "" {
+		s.printRequest(r, body)
+	}
 	if s.DryRun {
 		slog.Debug("Dry run: not making request")
 		return nil, nil
 	}
-	resp, err := s.Client.Do(r)
+	resp, err := s.send(r, body)
 	if err != nil {
 		return nil, fmt.Errorf("unable to execute request: %v", err)
 	}
+	// credentials that can be renewed are renewed, and the request sent
+	// again once, if the server rejects them.
+	if i, ok := s.Auth.(interface{ Invalidate() }); ok && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		slog.Debug("Request unauthorized, renewing credentials")
+		i.Invalidate()
+		if r.Body != nil {
+			r.Body = io.NopCloser(strings.NewReader(body))
+		}
+		if err := s.authenticate(r); err != nil {
+			return nil, fmt.Errorf("unable to authenticate: %v", err)
+		}
+		resp, err = s.send(r, body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to execute request: %v", err)
+		}
+	}
 	defer resp.Body.Close()
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read response body: %v", err)
 	}
+	if len(respBody) == 0 {
+		return &Result{StatusCode: resp.StatusCode}, nil
+	}
 	var prettyJSON bytes.Buffer
 	err = json.Indent(&prettyJSON, respBody, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to format JSON: %w", err)
 	}
-	return &Result{prettyJSON.String(), resp.StatusCode}, nil
+	return &Result{Output: prettyJSON.String(), StatusCode: resp.StatusCode}, nil
+}
+
+// authenticate adds the credentials the operation of the request requires.
+func (s *ServiceCommand) authenticate(r *http.Request) error {
+	if a, ok := s.Auth.(operationAuthenticator); ok {
+		path, _ := s.operationPath(r.Method, r.URL.Path)
+		return a.AuthenticateOperation(r, path)
+	}
+	return s.Auth.Authenticate(r)
+}
+
+// diff compares the live resource in the response with the local data,
+// ignoring fields the user can not set.
+func (s *ServiceCommand) diff(r *api.Resource, live *Result, local map[string]interface{}, format string) (*Result, error) {
+	var liveData map[string]interface{}
+	if err := json.Unmarshal([]byte(live.Output), &liveData); err != nil {
+		return nil, fmt.Errorf("unable to parse live resource: %v", err)
+	}
+	d, err := diffResources(
+		stripReadOnlyFields(liveData, r.Schema),
+		stripReadOnlyFields(local, r.Schema),
+		format,
+		useColor(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if d == "" {
+		return &Result{Output: "No differences found.", StatusCode: live.StatusCode}, nil
+	}
+	return &Result{Output: strings.TrimSuffix(d, "\n"), StatusCode: live.StatusCode, HasDiff: true}, nil
 }
 
 func (s *ServiceCommand) PrintHelp() string {
@@ -151,5 +338,17 @@ func (s *ServiceCommand) PrintHelp() string {
 	for _, r := range resources {
 		output.WriteString(fmt.Sprintf("  - %s\n", r))
 	}
+	output.WriteString("\nUse --tree to show the resources as a hierarchy.\n")
+	output.WriteString("\nAvailable commands:\n")
+	for _, c := range apiCommands {
+		if s.Policy.check(c.Name, c.Mutating) != nil {
+			continue
+		}
+		output.WriteString(fmt.Sprintf("  - %s: %s\n", c.Name, c.Short))
+	}
+	// authenticators that need credentials from the user describe them.
+	if h, ok := s.Auth.(interface{ Help() string }); ok {
+		output.WriteString("\n" + h.Help())
+	}
 	return output.String()
 }
@@ -1,9 +1,16 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
 )
 
 func TestService_ExecuteCommand_ListResources(t *testing.T) {
@@ -115,3 +122,173 @@ func TestNewServiceCommand_Insecure(t *testing.T) {
 		})
 	}
 }
+
+type stubAuthenticator struct {
+	calls int
+}
+
+func (a *stubAuthenticator) Authenticate(r *http.Request) error {
+	a.calls++
+	r.Header.Set("Authorization", "Bearer stub")
+	return nil
+}
+
+func TestService_Auth(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	svc, err := NewServiceCommand(getBookstoreAPI(server.URL), nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+	a := &stubAuthenticator{}
+	svc.Auth = a
+
+	if _, err := svc.Execute([]string{"publisher", "get", "acme"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got != "Bearer stub" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer stub")
+	}
+
+	// dry runs do not fetch credentials.
+	svc.DryRun = true
+	if _, err := svc.Execute([]string{"publisher", "get", "acme"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if a.calls != 1 {
+		t.Errorf("Authenticate() called %d times, want 1", a.calls)
+	}
+}
+
+// renewingAuthenticator issues a new token each time it is invalidated.
+type renewingAuthenticator struct {
+	generation int
+}
+
+func (a *renewingAuthenticator) Authenticate(r *http.Request) error {
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer token-%d", a.generation))
+	return nil
+}
+
+func (a *renewingAuthenticator) Invalidate() { a.generation++ }
+
+func TestService_Auth_RenewsAfterUnauthorized(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer token-0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	svc, err := NewServiceCommand(getBookstoreAPI(server.URL), nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+	svc.Auth = &renewingAuthenticator{}
+
+	result, err := svc.Execute([]string{"publisher", "get", "acme"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want 200", result.StatusCode)
+	}
+	if want := []string{"Bearer token-0", "Bearer token-1"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Authorization headers = %v, want %v", got, want)
+	}
+}
+
+// operationAuthenticatorStub records the OpenAPI paths of the operations it
+// authenticates.
+type operationAuthenticatorStub struct {
+	paths []string
+}
+
+func (a *operationAuthenticatorStub) Authenticate(r *http.Request) error {
+	return a.AuthenticateOperation(r, "")
+}
+
+func (a *operationAuthenticatorStub) AuthenticateOperation(r *http.Request, path string) error {
+	a.paths = append(a.paths, r.Method+" "+path)
+	return nil
+}
+
+func TestService_AuthenticateOperation(t *testing.T) {
+	svc, _ := newBookstoreService(t)
+	svc.OpenAPI = &openapi.OpenAPI{
+		Paths: map[string]*openapi.PathItem{
+			"/publishers/{publisher}": {Get: &openapi.Operation{}},
+		},
+	}
+	a := &operationAuthenticatorStub{}
+	svc.Auth = a
+	svc.Execute([]string{"publisher", "get", "acme"})
+	svc.Execute([]string{"publisher", "list"})
+	if want := []string{"GET /publishers/{publisher}", "GET "}; strings.Join(a.paths, ",") != strings.Join(want, ",") {
+		t.Errorf("authenticated operations = %q, want %q", a.paths, want)
+	}
+}
+
+func TestService_ExecuteContext_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	svc, err := NewServiceCommand(getBookstoreAPI(server.URL), nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.ExecuteContext(ctx, []string{"publisher", "get", "acme"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) && (err == nil || !strings.Contains(err.Error(), "context canceled")) {
+			t.Errorf("ExecuteContext() error = %v, want a cancelled request", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ExecuteContext() did not return after the context was cancelled")
+	}
+
+	// requests are not sent once the context is cancelled.
+	if _, err := svc.ExecuteContext(ctx, []string{"publisher", "list"}); err == nil || !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("ExecuteContext() with a cancelled context error = %v", err)
+	}
+}
+
+func TestService_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	svc, err := NewServiceCommand(getBookstoreAPI(server.URL), nil, false, false, false, "")
+	if err != nil {
+		t.Fatalf("Failed to create service command: %v", err)
+	}
+	svc.Client.Timeout = 50 * time.Millisecond
+	if _, err := svc.Execute([]string{"publisher", "get", "acme"}); err == nil || !strings.Contains(err.Error(), "Client.Timeout exceeded") {
+		t.Errorf("Execute() error = %v, want a timeout", err)
+	}
+}
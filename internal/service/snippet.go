@@ -0,0 +1,161 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// snippetFormats are the formats requests can be printed as, with --print-as.
+var snippetFormats = map[string]func(method, url string, headers [][2]string, body string) string{
+	"curl":   curlSnippet,
+	"httpie": httpieSnippet,
+	"go":     goSnippet,
+	"python": pythonSnippet,
+}
+
+// PrintFormats returns the formats requests can be printed as.
+func PrintFormats() []string {
+	formats := make([]string, 0, len(snippetFormats))
+	for f := range snippetFormats {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ValidatePrintFormat returns an error if requests can not be printed in the
+// format.
+func ValidatePrintFormat(format string) error {
+	if _, ok := snippetFormats[format]; !ok {
+		return fmt.Errorf("unsupported print format %q, must be one of %s", format, strings.Join(PrintFormats(), ", "))
+	}
+	return nil
+}
+
+// printRequest prints the request as a command or code snippet in the
+// PrintAs format. PrintMasked redacts it like logs are.
+func (s *ServiceCommand) printRequest(r *http.Request, body string) {
+	if snippet := s.snippet(r, body); snippet != "" {
+		fmt.Println(snippet)
+	}
+}
+
+// snippet returns the request as a command or code snippet in the PrintAs
+// format, with the headers it is sent with.
+func (s *ServiceCommand) snippet(r *http.Request, body string) string {
+	format, ok := snippetFormats[s.PrintAs]
+	if !ok {
+		return ""
+	}
+	url, header := r.URL.String(), r.Header
+	if s.PrintMasked {
+		url, header, body = s.Redact.URL(r.URL), s.Redact.Headers(r.Header), s.Redact.Body(body)
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := [][2]string{}
+	for _, name := range names {
+		for _, v := range header[name] {
+			headers = append(headers, [2]string{name, v})
+		}
+	}
+	return format(r.Method, url, headers, body)
+}
+
+// shellQuote quotes the value for POSIX shells.
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func curlSnippet(method, url string, headers [][2]string, body string) string {
+	lines := []string{"curl -X " + method + " " + shellQuote(url)}
+	for _, h := range headers {
+		lines = append(lines, "-H "+shellQuote(h[0]+": "+h[1]))
+	}
+	if body != "" {
+		lines = append(lines, "--data-raw "+shellQuote(body))
+	}
+	return strings.Join(lines, " \\\n  ")
+}
+
+func httpieSnippet(method, url string, headers [][2]string, body string) string {
+	lines := []string{"http " + method + " " + shellQuote(url)}
+	for _, h := range headers {
+		lines = append(lines, shellQuote(h[0]+":"+h[1]))
+	}
+	if body != "" {
+		lines = append(lines, "--raw "+shellQuote(body))
+	}
+	return strings.Join(lines, " \\\n  ")
+}
+
+func goSnippet(method, url string, headers [][2]string, body string) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if body != "" {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	bodyArg := "nil"
+	if body != "" {
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", goString(body))
+		bodyArg = "body"
+	}
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(method), strconv.Quote(url), bodyArg)
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	for _, h := range headers {
+		fmt.Fprintf(&b, "\treq.Header.Add(%s, %s)\n", strconv.Quote(h[0]), strconv.Quote(h[1]))
+	}
+	b.WriteString("\tresp, err := http.DefaultClient.Do(req)\n")
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tdefer resp.Body.Close()\n")
+	b.WriteString("\tout, err := io.ReadAll(resp.Body)\n")
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tfmt.Println(resp.Status)\n\tfmt.Println(string(out))\n}")
+	return b.String()
+}
+
+// goString returns a Go literal of the value, raw if possible so JSON bodies
+// stay readable.
+func goString(v string) string {
+	if !strings.ContainsAny(v, "`\r") {
+		return "`" + v + "`"
+	}
+	return strconv.Quote(v)
+}
+
+func pythonSnippet(method, url string, headers [][2]string, body string) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\nresponse = requests.request(\n")
+	fmt.Fprintf(&b, "    %s,\n    %s,\n", pythonString(method), pythonString(url))
+	if len(headers) > 0 {
+		b.WriteString("    headers={\n")
+		for _, h := range headers {
+			fmt.Fprintf(&b, "        %s: %s,\n", pythonString(h[0]), pythonString(h[1]))
+		}
+		b.WriteString("    },\n")
+	}
+	if body != "" {
+		fmt.Fprintf(&b, "    data=%s,\n", pythonString(body))
+	}
+	b.WriteString(")\nprint(response.status_code)\nprint(response.text)")
+	return b.String()
+}
+
+// pythonString returns a Python literal of the value. JSON strings are valid
+// Python strings.
+func pythonString(v string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "https://bookstore.example.com/publishers?id=acme", nil)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer secret-token")
+	body := `{"description": "O'Reilly"}`
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"curl", `curl -X POST 'https://bookstore.example.com/publishers?id=acme' \
+  -H 'Authorization: Bearer secret-token' \
+  -H 'Content-Type: application/json' \
+  --data-raw '{"description": "O'\''Reilly"}'`},
+		{"httpie", `http POST 'https://bookstore.example.com/publishers?id=acme' \
+  'Authorization:Bearer secret-token' \
+  'Content-Type:application/json' \
+  --raw '{"description": "O'\''Reilly"}'`},
+		{"python", `import requests
+
+response = requests.request(
+    "POST",
+    "https://bookstore.example.com/publishers?id=acme",
+    headers={
+        "Authorization": "Bearer secret-token",
+        "Content-Type": "application/json",
+    },
+    data="{\"description\": \"O'Reilly\"}",
+)
+print(response.status_code)
+print(response.text)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s := &ServiceCommand{PrintAs: tt.format}
+			if got := s.snippet(r, body); got != tt.want {
+				t.Errorf("snippet() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	s := &ServiceCommand{PrintAs: "go"}
+	snippet := s.snippet(r, body)
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", snippet, 0); err != nil {
+		t.Errorf("go snippet does not parse: %v\n%s", err, snippet)
+	}
+	if !strings.Contains(snippet, `req.Header.Add("Authorization", "Bearer secret-token")`) || !strings.Contains(snippet, "`"+body+"`") {
+		t.Errorf("go snippet =\n%s", snippet)
+	}
+}
+
+func TestSnippet_Masked(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "https://bookstore.example.com/publishers/acme", nil)
+	r.Header.Set("Authorization", "Bearer secret-token")
+	r.Header.Set("X-Api-Key", "secret-key")
+	r.Header.Set("X-Trace", "1")
+	s := &ServiceCommand{PrintAs: "curl", PrintMasked: true}
+	got := s.snippet(r, "")
+	want := `curl -X GET 'https://bookstore.example.com/publishers/acme' \
+  -H 'Authorization: REDACTED' \
+  -H 'X-Api-Key: REDACTED' \
+  -H 'X-Trace: 1'`
+	if got != want {
+		t.Errorf("snippet() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestService_PrintAs_DryRun(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	svc.DryRun = true
+	svc.PrintAs = "curl"
+	if _, err := svc.Execute([]string{"publisher", "create", "acme", "--yes"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if m := f.mutations(); len(m) != 0 {
+		t.Errorf("mutations = %v, want the dry run to not send the request", m)
+	}
+	if err := ValidatePrintFormat("wget"); err == nil || !strings.Contains(err.Error(), "curl, go, httpie, python") {
+		t.Errorf("ValidatePrintFormat() error = %v", err)
+	}
+}
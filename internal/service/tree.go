@@ -0,0 +1,191 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aep-dev/aep-lib-go/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+const (
+	treeFormatTree = "tree"
+	treeFormatJSON = "json"
+)
+
+// PrintTree returns the resource hierarchy of the API, derived from the
+// resource patterns, as an indented tree.
+func (s *ServiceCommand) PrintTree() string {
+	var output strings.Builder
+	var write func(r *api.Resource, indent string)
+	write = func(r *api.Resource, indent string) {
+		output.WriteString(fmt.Sprintf("%s%s (%s)\n", indent, r.Singular, strings.Join(r.PatternElems(), "/")))
+		for _, child := range childResources(&s.API, r) {
+			write(child, indent+"  ")
+		}
+	}
+	for _, r := range hierarchyRoots(&s.API) {
+		write(r, "")
+	}
+	return strings.TrimSuffix(output.String(), "\n")
+}
+
+// hierarchyRoots returns the resources that are not the child of another
+// resource in the API. Unlike rootResources, this includes resources whose
+// parents are not part of the API.
+func hierarchyRoots(a *api.API) []*api.Resource {
+	children := map[*api.Resource]bool{}
+	for _, r := range sortedResources(a) {
+		for _, child := range childResources(a, r) {
+			children[child] = true
+		}
+	}
+	roots := []*api.Resource{}
+	for _, r := range sortedResources(a) {
+		if !children[r] {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// treeNode is a live resource and its descendants.
+type treeNode struct {
+	Path     string      `json:"path"`
+	Type     string      `json:"type"`
+	Children []*treeNode `json:"children,omitempty"`
+}
+
+func (s *ServiceCommand) tree(args []string) (*Result, error) {
+	var depth int
+	var format string
+	run := false
+
+	c := &cobra.Command{
+		Use:   "tree [path]",
+		Short: "Show a resource and its descendants",
+		Long: "List the descendants of the resource at path, e.g. publishers/acme, by listing\n" +
+			"each of its child collections. Without a path, the top-level resources are listed.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			run = true
+		},
+	}
+	c.Flags().IntVar(&depth, "depth", 2, "Number of levels of descendants to list, or 0 for no limit")
+	c.Flags().StringVar(&format, "format", treeFormatTree, fmt.Sprintf("Output format (%s, %s)", treeFormatTree, treeFormatJSON))
+
+	var stdout strings.Builder
+	c.SetOut(&stdout)
+	c.SetArgs(args)
+	if err := c.Execute(); err != nil {
+		return &Result{Output: stdout.String()}, err
+	}
+	if !run {
+		return &Result{Output: stdout.String()}, nil
+	}
+	if format != treeFormatTree && format != treeFormatJSON {
+		return nil, fmt.Errorf("unknown format %q, expected one of: %s, %s", format, treeFormatTree, treeFormatJSON)
+	}
+	if depth < 0 {
+		return nil, fmt.Errorf("--depth must not be negative, got %d", depth)
+	}
+	if depth == 0 {
+		depth = -1
+	}
+
+	var nodes []*treeNode
+	if len(c.Flags().Args()) == 1 {
+		path := strings.Trim(c.Flags().Arg(0), "/")
+		r, err := resourceForPath(&s.API, path)
+		if err != nil {
+			return nil, err
+		}
+		node := &treeNode{Path: path, Type: r.Singular}
+		if node.Children, err = s.treeChildren(path, r, depth); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	} else {
+		for _, r := range rootResources(&s.API) {
+			if r.Methods.List == nil {
+				slog.Warn("Skipping resource without a list method", "resource", r.Singular)
+				continue
+			}
+			children, err := s.treeCollection("", r, depth)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, children...)
+		}
+	}
+
+	if format == treeFormatJSON {
+		var v interface{} = nodes
+		if len(c.Flags().Args()) == 1 {
+			v = nodes[0]
+		}
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal tree: %v", err)
+		}
+		return &Result{Output: string(b)}, nil
+	}
+	var output strings.Builder
+	var write func(n *treeNode, parent, indent string)
+	write = func(n *treeNode, parent, indent string) {
+		output.WriteString(fmt.Sprintf("%s%s\n", indent, strings.TrimPrefix(n.Path, parent+"/")))
+		for _, child := range n.Children {
+			write(child, n.Path, indent+"  ")
+		}
+	}
+	for _, n := range nodes {
+		write(n, "", "")
+	}
+	return &Result{Output: strings.TrimSuffix(output.String(), "\n")}, nil
+}
+
+// treeChildren lists the descendants of the resource at path, up to levels
+// levels down, or all of them if levels is negative.
+func (s *ServiceCommand) treeChildren(path string, r *api.Resource, levels int) ([]*treeNode, error) {
+	if levels == 0 {
+		return nil, nil
+	}
+	children := []*treeNode{}
+	for _, child := range childResources(&s.API, r) {
+		if child.Methods.List == nil {
+			slog.Warn("Skipping resource without a list method", "resource", child.Singular, "parent", path)
+			continue
+		}
+		nodes, err := s.treeCollection(path, child, levels)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, nodes...)
+	}
+	return children, nil
+}
+
+// treeCollection lists the resources in the collection of r under the
+// parent path as the first of levels levels, followed by their descendants.
+func (s *ServiceCommand) treeCollection(parent string, r *api.Resource, levels int) ([]*treeNode, error) {
+	collection := childCollectionPath(parent, r)
+	items, err := s.listAll(collection)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*treeNode{}
+	for _, item := range items {
+		p, err := itemPath(collection, item)
+		if err != nil {
+			return nil, err
+		}
+		node := &treeNode{Path: p, Type: r.Singular}
+		if node.Children, err = s.treeChildren(p, r, levels-1); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintTree(t *testing.T) {
+	svc, _ := newBookstoreService(t)
+	result, err := svc.Execute([]string{"--tree"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"publisher (publishers/{publisher_id})",
+		"  book (publishers/{publisher_id}/books/{book_id})",
+		"    book-edition (publishers/{publisher_id}/books/{book_id}/editions/{book_edition_id})",
+	}, "\n")
+	if result.Output != want {
+		t.Errorf("Execute() =\n%s\nwant\n%s", result.Output, want)
+	}
+}
+
+func TestTree(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	putBookstoreTree(f)
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "resource",
+			args: []string{"tree", "publishers/acme"},
+			want: []string{
+				"publishers/acme",
+				"  books/a",
+				"    editions/1",
+				"    editions/2",
+				"  books/b",
+			},
+		},
+		{
+			name: "depth",
+			args: []string{"tree", "publishers/acme", "--depth", "1"},
+			want: []string{
+				"publishers/acme",
+				"  books/a",
+				"  books/b",
+			},
+		},
+		{
+			name: "all",
+			args: []string{"tree", "--depth", "0"},
+			want: []string{
+				"publishers/acme",
+				"  books/a",
+				"    editions/1",
+				"    editions/2",
+				"  books/b",
+				"publishers/other",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := svc.Execute(tt.args)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if want := strings.Join(tt.want, "\n"); result.Output != want {
+				t.Errorf("Execute() =\n%s\nwant\n%s", result.Output, want)
+			}
+		})
+	}
+}
+
+func TestTree_JSON(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	putBookstoreTree(f)
+
+	result, err := svc.Execute([]string{"tree", "publishers/acme/books/a", "--format", "json"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var got treeNode
+	if err := json.Unmarshal([]byte(result.Output), &got); err != nil {
+		t.Fatalf("Unable to parse output %q: %v", result.Output, err)
+	}
+	want := treeNode{
+		Path: "publishers/acme/books/a",
+		Type: "book",
+		Children: []*treeNode{
+			{Path: "publishers/acme/books/a/editions/1", Type: "book-edition"},
+			{Path: "publishers/acme/books/a/editions/2", Type: "book-edition"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Execute() = %s", result.Output)
+	}
+}
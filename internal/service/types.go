@@ -5,4 +5,17 @@ type Result struct {
 	// StatusCode should be 0 for undefined, or
 	// the HTTP status code of the response.
 	StatusCode int
+	// HasDiff is set by commands that compare resources
+	// when a difference was found.
+	HasDiff bool
+}
+
+// apiCommand is a command that operates on the API as a whole,
+// rather than on a single resource.
+type apiCommand struct {
+	Name  string
+	Short string
+	// Mutating is set for commands that change resources.
+	Mutating bool
+	Run      func(s *ServiceCommand, args []string) (*Result, error)
 }
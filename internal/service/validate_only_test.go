@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aep-dev/aep-lib-go/pkg/openapi"
+)
+
+func TestService_ValidateOnly(t *testing.T) {
+	svc, f := newBookstoreService(t)
+	validateOnly := []openapi.Parameter{{Name: "validate_only", In: "query"}}
+	svc.OpenAPI = &openapi.OpenAPI{
+		Paths: map[string]*openapi.PathItem{
+			"/publishers":             {Post: &openapi.Operation{Parameters: validateOnly}},
+			"/publishers/{publisher}": {Delete: &openapi.Operation{Parameters: validateOnly}, Patch: &openapi.Operation{}},
+		},
+	}
+	f.put("publishers/acme", nil)
+	// changes that are only validated are not confirmed.
+	svc.Protected = true
+	svc.prompt = func(q string) (string, error) {
+		t.Fatalf("unexpected prompt %q", q)
+		return "", nil
+	}
+
+	result, err := svc.Execute([]string{"publisher", "create", "globex", "--validate-only"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.HasPrefix(result.Output, "Validate only: the server only validated the request, and nothing was changed.\n") {
+		t.Errorf("Output = %q, want the validate only note first", result.Output)
+	}
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme", "--validate-only"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"POST publishers?id=globex&validate_only=true", "DELETE publishers/acme?validate_only=true"}
+	if m := f.mutations(); strings.Join(m, ",") != strings.Join(want, ",") {
+		t.Errorf("mutations = %v, want %v", m, want)
+	}
+
+	// the flag is only available if the operation declares the parameter.
+	if _, err := svc.Execute([]string{"publisher", "update", "acme", "--validate-only"}); err == nil || !strings.Contains(err.Error(), "unknown flag: --validate-only") {
+		t.Errorf("Execute() update with --validate-only error = %v", err)
+	}
+	if _, err := svc.Execute([]string{"publisher", "delete", "acme", "--validate-only", "--recursive"}); err == nil {
+		t.Error("Execute() with --validate-only and --recursive did not fail")
+	}
+}
@@ -0,0 +1,142 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aep-dev/aepcli/internal/redact"
+)
+
+// Formats are the formats timings are reported in.
+var Formats = []string{"human", "json"}
+
+// Request is the timing of a request.
+type Request struct {
+	Method string
+	URL    string
+	Status int
+	Phases
+}
+
+// Transport is an http.RoundTripper that reports the phases of every
+// request, and their totals across all requests, e.g. the pages of a list.
+type Transport struct {
+	next   http.RoundTripper
+	out    io.Writer
+	format string
+	redact *redact.Redactor
+
+	mu     sync.Mutex
+	totals Phases
+	count  int
+}
+
+// NewTransport returns a transport sending requests with next, or
+// http.DefaultTransport if it is nil, and writing their timings to out in
+// the format, human or json. URLs are redacted with the redactor.
+func NewTransport(next http.RoundTripper, out io.Writer, format string, redactor *redact.Redactor) (*Transport, error) {
+	if !slices.Contains(Formats, format) {
+		return nil, fmt.Errorf("unsupported timing format %q, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, out: out, format: format, redact: redactor}, nil
+}
+
+// RoundTrip sends the request, reads the response, and reports its timing.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	traced, trace := Start(req)
+	resp, err := t.next.RoundTrip(traced)
+	if err != nil {
+		return nil, err
+	}
+	// the response is read here, so the transfer is part of the timing.
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	r := Request{Method: req.Method, URL: t.redact.URL(req.URL), Status: resp.StatusCode, Phases: trace.Done()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count++
+	t.totals.Blocked += r.Blocked
+	t.totals.DNS += r.DNS
+	t.totals.Connect += r.Connect
+	t.totals.TLS += r.TLS
+	t.totals.Send += r.Send
+	t.totals.Wait += r.Wait
+	t.totals.Receive += r.Receive
+	t.totals.Total += r.Total
+	if t.format == "json" {
+		t.writeJSON(map[string]any{
+			"method": r.Method,
+			"url":    r.URL,
+			"status": r.Status,
+			"reused": r.Reused,
+			"timing": phaseMilliseconds(r.Phases),
+		})
+	} else {
+		fmt.Fprintf(t.out, "%s %s %d  %s\n", r.Method, r.URL, r.Status, humanPhases(r.Phases))
+	}
+	return resp, nil
+}
+
+// PrintTotals reports the totals of the phases of all requests, if there
+// were any.
+func (t *Transport) PrintTotals() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count == 0 {
+		return
+	}
+	if t.format == "json" {
+		t.writeJSON(map[string]any{"totals": map[string]any{
+			"requests": t.count,
+			"timing":   phaseMilliseconds(t.totals),
+		}})
+		return
+	}
+	fmt.Fprintf(t.out, "Total: %d requests  %s\n", t.count, humanPhases(t.totals))
+}
+
+func (t *Transport) writeJSON(v any) {
+	b, _ := json.Marshal(v)
+	fmt.Fprintln(t.out, string(b))
+}
+
+// humanPhases formats the phases like curl -w: dns, connect and tls are the
+// connection, server the time until the first byte of the response, and
+// transfer the time reading it.
+func humanPhases(p Phases) string {
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.1fms", milliseconds(d)) }
+	return fmt.Sprintf("dns=%s connect=%s tls=%s send=%s server=%s transfer=%s total=%s",
+		ms(p.DNS), ms(p.Connect), ms(p.TLS), ms(p.Send), ms(p.Wait), ms(p.Receive), ms(p.Total))
+}
+
+func phaseMilliseconds(p Phases) map[string]float64 {
+	return map[string]float64{
+		"blocked_ms":  milliseconds(p.Blocked),
+		"dns_ms":      milliseconds(p.DNS),
+		"connect_ms":  milliseconds(p.Connect),
+		"tls_ms":      milliseconds(p.TLS),
+		"send_ms":     milliseconds(p.Send),
+		"server_ms":   milliseconds(p.Wait),
+		"transfer_ms": milliseconds(p.Receive),
+		"total_ms":    milliseconds(p.Total),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,99 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{"results": []}`))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	transport, err := NewTransport(server.Client().Transport, &out, "human", nil)
+	if err != nil {
+		t.Fatalf("NewTransport() error = %v", err)
+	}
+	client := &http.Client{Transport: transport}
+	for _, page := range []string{"", "?page_token=2"} {
+		resp, err := client.Get(server.URL + "/publishers" + page)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	transport.PrintTotals()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output =\n%s\nwant 2 requests and the totals", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "GET "+server.URL+"/publishers 200  dns=") || strings.Contains(lines[0], "tls=0.0ms") {
+		t.Errorf("first request = %s, want a TLS handshake", lines[0])
+	}
+	if !strings.Contains(lines[1], "tls=0.0ms") {
+		t.Errorf("second request = %s, want the connection reused", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "Total: 2 requests  ") {
+		t.Errorf("totals = %s", lines[2])
+	}
+}
+
+func TestTransport_JSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	transport, err := NewTransport(nil, &out, "json", nil)
+	if err != nil {
+		t.Fatalf("NewTransport() error = %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/publishers/acme?key=secret")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	transport.PrintTotals()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output =\n%s", out.String())
+	}
+	var request struct {
+		URL    string             `json:"url"`
+		Status int                `json:"status"`
+		Timing map[string]float64 `json:"timing"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &request); err != nil {
+		t.Fatalf("invalid JSON %s: %v", lines[0], err)
+	}
+	if request.Status != http.StatusNotFound || request.URL != server.URL+"/publishers/acme?key=REDACTED" {
+		t.Errorf("request = %+v", request)
+	}
+	if request.Timing["server_ms"] < 10 || request.Timing["total_ms"] < request.Timing["server_ms"] {
+		t.Errorf("timing = %v, want at least 10ms of server time", request.Timing)
+	}
+	var totals struct {
+		Totals struct {
+			Requests int `json:"requests"`
+		} `json:"totals"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &totals); err != nil || totals.Totals.Requests != 1 {
+		t.Errorf("totals = %s, %v", lines[1], err)
+	}
+
+	if _, err := NewTransport(nil, &out, "xml", nil); err == nil {
+		t.Error("NewTransport() with an unsupported format did not fail")
+	}
+}
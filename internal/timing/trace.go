@@ -0,0 +1,113 @@
+// Package timing measures the phases of HTTP requests with httptrace, for
+// HAR files, and reports them with Transport.
+package timing
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Phases is how long each phase of a request took. Phases that did not
+// happen, e.g. DNS and Connect on a reused connection, are 0.
+type Phases struct {
+	// Start is when the request was sent.
+	Start time.Time
+	// Blocked is the time waiting for a connection, other than DNS, Connect
+	// and TLS.
+	Blocked time.Duration
+	DNS     time.Duration
+	// Connect is the TCP connection, without the TLS handshake.
+	Connect time.Duration
+	TLS     time.Duration
+	// Send is the time writing the request, and Wait the time until the
+	// first byte of the response, i.e. the server time.
+	Send time.Duration
+	Wait time.Duration
+	// Receive is the time reading the response.
+	Receive time.Duration
+	Total   time.Duration
+	// Reused is whether the request was sent on an existing connection.
+	Reused bool
+}
+
+// Trace records the times of the events of a request.
+type Trace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// Start returns the request with a trace of its events. Done must be called
+// once the response was read.
+func Start(r *http.Request) (*http.Request, *Trace) {
+	t := &Trace{start: time.Now()}
+	set := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// only the first attempt to connect is kept, e.g. the first of
+		// several addresses.
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart:      func(string, string) { set(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart: func() { set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			set(&t.gotConn)
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+	return r.WithContext(httptrace.WithClientTrace(r.Context(), trace)), t
+}
+
+// Done returns the phases of the request, which ended now.
+func (t *Trace) Done() Phases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := time.Now()
+	p := Phases{
+		Start:   t.start,
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connectStart, t.connectDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		Send:    between(t.gotConn, t.wroteRequest),
+		Wait:    between(t.wroteRequest, t.firstByte),
+		Receive: between(t.firstByte, end),
+		Total:   end.Sub(t.start),
+		Reused:  t.reused,
+	}
+	if blocked := between(t.start, t.gotConn) - p.DNS - p.Connect - p.TLS; blocked > 0 {
+		p.Blocked = blocked
+	}
+	return p
+}
+
+// between returns the time from start to end, or 0 if either did not
+// happen.
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}